@@ -0,0 +1,59 @@
+package endpoints
+
+import (
+	"backend/internal/model"
+	"context"
+)
+
+type conversationAccess struct {
+	chatRepo        model.UserChatRepository
+	userGroupRepo   model.UserGroupRepository
+	userChannelRepo model.UserChannelRepository
+}
+
+func newConversationAccess(chatRepo model.UserChatRepository, userGroupRepo model.UserGroupRepository,
+	userChannelRepo model.UserChannelRepository) *conversationAccess {
+	return &conversationAccess{
+		userChannelRepo: userChannelRepo,
+		userGroupRepo:   userGroupRepo,
+		chatRepo:        chatRepo,
+	}
+}
+
+// canRead reports whether userID may read the private chat, group or channel
+// identified by chatID, using the same rules as the history endpoints.
+func (a *conversationAccess) canRead(ctx context.Context, userID, chatID uint64, chatType model.MessageType) (bool, error) {
+	switch chatType {
+	case model.TypePV:
+		chats, err := a.chatRepo.Get(ctx, model.ChatInterface{
+			ID: &chatID,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		return len(chats) != 0 && (chats[0].UserID == userID || chats[0].ReceiverID == userID), nil
+	case model.TypeGP:
+		users, err := a.userGroupRepo.Get(ctx, model.UserGroupInterface{
+			GroupID: &chatID,
+			UserID:  &userID,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		return len(users) != 0, nil
+	case model.TypeCH:
+		users, err := a.userChannelRepo.Get(ctx, model.UserChannelInterface{
+			ChannelID: &chatID,
+			UserID:    &userID,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		return len(users) != 0, nil
+	}
+
+	return false, nil
+}
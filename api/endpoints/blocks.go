@@ -0,0 +1,97 @@
+package endpoints
+
+import (
+	"backend/internal/blocking"
+	"backend/internal/model"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+)
+
+// checkBlocked is how handlers ask the blocking service whether actorID may
+// reach targetID. It fails with 403 when targetID has blocked them.
+func checkBlocked(c echo.Context, blocks *blocking.Service, actorID, targetID uint64) error {
+	err := blocks.Check(c.Request().Context(), actorID, targetID)
+	if errors.Is(err, blocking.ErrBlocked) {
+		return echo.NewHTTPError(http.StatusForbidden, map[string]string{
+			"msg": err.Error(),
+		})
+	}
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return nil
+}
+
+func (u *User) GetBlocks(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	blocks, err := u.blocks.List(c.Request().Context(), userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, blocks)
+}
+
+func (u *User) BlockUser(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	targetID, err := strconv.ParseUint(c.Param("userid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	if targetID == userid {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "you can not block yourself",
+		})
+	}
+
+	users, err := u.repo.Get(c.Request().Context(), model.UserInterface{
+		ID: &targetID,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if len(users) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "user not found",
+		})
+	}
+
+	if err = u.blocks.Block(c.Request().Context(), userid, targetID); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "user blocked",
+	})
+}
+
+func (u *User) UnblockUser(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	targetID, err := strconv.ParseUint(c.Param("userid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ok, err := u.blocks.Unblock(c.Request().Context(), userid, targetID)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "user is not blocked",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "user unblocked",
+	})
+}
@@ -0,0 +1,592 @@
+package endpoints
+
+import (
+	"backend/internal/model"
+	"backend/internal/mwares"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+var channelHandlePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{4,31}$`)
+
+type Channel struct {
+	repo            model.ChannelRepository
+	messageRepo     model.MessageRepository
+	userChannelRepo model.UserChannelRepository
+	viewRepo        model.ChannelViewRepository
+}
+
+func NewChannel(repo model.ChannelRepository, messageRepo model.MessageRepository, userChannelRepo model.UserChannelRepository,
+	viewRepo model.ChannelViewRepository) *Channel {
+	return &Channel{
+		userChannelRepo: userChannelRepo,
+		messageRepo:     messageRepo,
+		viewRepo:        viewRepo,
+		repo:            repo,
+	}
+}
+
+func parsePageParams(c echo.Context) (*uint64, int, error) {
+	var before *uint64
+	if v := c.QueryParam("before"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, 0, err
+		}
+		before = &id
+	}
+
+	limit := defaultPageSize
+	if v := c.QueryParam("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l <= 0 {
+			return nil, 0, echo.ErrBadRequest
+		}
+		if l > maxPageSize {
+			l = maxPageSize
+		}
+		limit = l
+	}
+
+	return before, limit, nil
+}
+
+func (ch *Channel) handleAvailable(c echo.Context, handle string, channelID uint64) (bool, error) {
+	channels, err := ch.repo.Get(c.Request().Context(), model.ChannelInterface{
+		Handle: &handle,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(channels) == 0 || channels[0].ChannelID == channelID, nil
+}
+
+func (ch *Channel) getRole(c echo.Context, channelID, userID uint64) (*model.UserChannel, error) {
+	subs, err := ch.userChannelRepo.Get(c.Request().Context(), model.UserChannelInterface{
+		ChannelID: &channelID,
+		UserID:    &userID,
+	})
+	if err != nil || len(subs) == 0 {
+		return nil, err
+	}
+
+	return &subs[0], nil
+}
+
+func (ch *Channel) channelResponse(c echo.Context, channel model.Channel) (echo.Map, error) {
+	count, err := ch.userChannelRepo.Count(c.Request().Context(), channel.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	return echo.Map{
+		"channel":     channel,
+		"subscribers": count,
+	}, nil
+}
+
+func (ch *Channel) NewChannel(c echo.Context) error {
+	id := c.Get("userID")
+	ownerid, _ := id.(uint64)
+
+	name := c.FormValue("name")
+	if name == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "channel name can not be empty",
+		})
+	}
+
+	handle := strings.ToLower(c.FormValue("handle"))
+	if !channelHandlePattern.MatchString(handle) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "channel handle must be 5-32 letters, digits or underscores and start with a letter",
+		})
+	}
+
+	ok, err := ch.handleAvailable(c, handle, 0)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if !ok {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"msg": "channel handle is taken",
+		})
+	}
+
+	channelID, err := ch.repo.Create(c.Request().Context(), model.Channel{
+		Name:        name,
+		Description: c.FormValue("description"),
+		Handle:      handle,
+		Owner:       ownerid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"msg":       "channel created",
+		"channelID": channelID,
+	})
+}
+
+func (ch *Channel) GetChannel(c echo.Context) error {
+	channelid, err := strconv.ParseUint(c.Param("channelid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	channels, err := ch.repo.Get(c.Request().Context(), model.ChannelInterface{
+		ID: &channelid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if len(channels) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "channel not found",
+		})
+	}
+
+	res, err := ch.channelResponse(c, channels[0])
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func (ch *Channel) GetChannelByHandle(c echo.Context) error {
+	handle := strings.ToLower(c.Param("handle"))
+
+	channels, err := ch.repo.Get(c.Request().Context(), model.ChannelInterface{
+		Handle: &handle,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if len(channels) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "channel not found",
+		})
+	}
+
+	res, err := ch.channelResponse(c, channels[0])
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func (ch *Channel) GetChannels(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	subs, err := ch.userChannelRepo.Get(c.Request().Context(), model.UserChannelInterface{
+		UserID: &userid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, subs)
+}
+
+func (ch *Channel) UpdateChannel(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	channelid, err := strconv.ParseUint(c.Param("channelid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	sub, err := ch.getRole(c, channelid, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if sub == nil || !sub.Role.CanPost() {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"msg": "only channel admins can edit the channel",
+		})
+	}
+
+	update := model.Channel{
+		ChannelID:   channelid,
+		Name:        c.FormValue("name"),
+		Description: c.FormValue("description"),
+	}
+
+	if v := c.FormValue("handle"); v != "" {
+		handle := strings.ToLower(v)
+		if !channelHandlePattern.MatchString(handle) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "channel handle must be 5-32 letters, digits or underscores and start with a letter",
+			})
+		}
+
+		ok, err := ch.handleAvailable(c, handle, channelid)
+		if err != nil {
+			return echo.ErrInternalServerError
+		}
+		if !ok {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"msg": "channel handle is taken",
+			})
+		}
+		update.Handle = handle
+	}
+
+	if err = ch.repo.Update(c.Request().Context(), update); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "channel updated",
+	})
+}
+
+func (ch *Channel) DeleteChannel(c echo.Context) error {
+	id := c.Get("userID")
+	ownerid, _ := id.(uint64)
+
+	channelid, err := strconv.ParseUint(c.Param("channelid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	channels, err := ch.repo.Get(c.Request().Context(), model.ChannelInterface{
+		ID:      &channelid,
+		OwnerID: &ownerid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if len(channels) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "channel not found",
+		})
+	}
+
+	if err = ch.repo.Delete(c.Request().Context(), model.ChannelInterface{
+		ID:      &channelid,
+		OwnerID: &ownerid,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "channel deleted",
+	})
+}
+
+func (ch *Channel) Subscribe(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	channelid, err := strconv.ParseUint(c.Param("channelid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	channels, err := ch.repo.Get(c.Request().Context(), model.ChannelInterface{
+		ID: &channelid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if len(channels) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "channel not found",
+		})
+	}
+
+	sub, err := ch.getRole(c, channelid, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if sub != nil {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"msg": "already subscribed",
+		})
+	}
+
+	if err = ch.userChannelRepo.Create(c.Request().Context(), model.UserChannel{
+		ChannelID: channelid,
+		UserID:    userid,
+		Role:      model.ChannelSubscriber,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusCreated, map[string]string{
+		"msg": "subscribed",
+	})
+}
+
+func (ch *Channel) Unsubscribe(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	channelid, err := strconv.ParseUint(c.Param("channelid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	sub, err := ch.getRole(c, channelid, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if sub == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "you are not subscribed to this channel",
+		})
+	}
+	if sub.Role == model.ChannelOwner {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "the owner can not unsubscribe, delete the channel instead",
+		})
+	}
+
+	if err = ch.userChannelRepo.Delete(c.Request().Context(), model.UserChannelInterface{
+		ChannelID: &channelid,
+		UserID:    &userid,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "unsubscribed",
+	})
+}
+
+func (ch *Channel) SetAdmin(c echo.Context) error {
+	id := c.Get("userID")
+	ownerid, _ := id.(uint64)
+
+	channelid, err := strconv.ParseUint(c.Param("channelid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	targetid, err := strconv.ParseUint(c.Param("userid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	owner, err := ch.getRole(c, channelid, ownerid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if owner == nil || owner.Role != model.ChannelOwner {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"msg": "only the channel owner can manage admins",
+		})
+	}
+
+	target, err := ch.getRole(c, channelid, targetid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if target == nil || target.Role == model.ChannelOwner {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "user is not a subscriber of this channel",
+		})
+	}
+
+	role := model.ChannelAdmin
+	if c.Request().Method == http.MethodDelete {
+		role = model.ChannelSubscriber
+	}
+
+	if err = ch.userChannelRepo.Update(c.Request().Context(), model.UserChannel{
+		UserChannelID: target.UserChannelID,
+		Role:          role,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "channel role updated",
+	})
+}
+
+func (ch *Channel) NewChannelMessage(c echo.Context) error {
+	id := c.Get("userID")
+	senderid, _ := id.(uint64)
+
+	channelid, err := strconv.ParseUint(c.Param("channelid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	sub, err := ch.getRole(c, channelid, senderid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if sub == nil || !sub.Role.CanPost() {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"msg": "only channel admins can post",
+		})
+	}
+
+	messageContent := c.FormValue("content")
+	payload := json.RawMessage(c.FormValue("payload"))
+	kind, err := model.ValidateUserMessage(model.ContentKind(c.FormValue("kind")), messageContent, payload)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": err.Error(),
+		})
+	}
+
+	messageID, err := ch.messageRepo.Create(c.Request().Context(), model.Message{
+		ChatID:   channelid,
+		SenderID: senderid,
+		Type:     model.TypeCH,
+		Kind:     kind,
+		IsRead:   "false",
+		Content:  messageContent,
+		Payload:  payload,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"msg":       "message sent",
+		"messageID": messageID,
+	})
+}
+
+func (ch *Channel) DeleteChannelMessage(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	channelid, err := strconv.ParseUint(c.Param("channelid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	messageid, err := strconv.ParseUint(c.Param("messageid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	sub, err := ch.getRole(c, channelid, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if sub == nil || !sub.Role.CanPost() {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"msg": "only channel admins can delete posts",
+		})
+	}
+
+	chatType := model.TypeCH
+	if err = ch.messageRepo.Delete(c.Request().Context(), model.MessageInterface{
+		ID:     &messageid,
+		ChatID: &channelid,
+		Type:   &chatType,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "message deleted",
+	})
+}
+
+func (ch *Channel) GetChannelMessages(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	channelid, err := strconv.ParseUint(c.Param("channelid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	sub, err := ch.getRole(c, channelid, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if sub == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "you are not subscribed to this channel",
+		})
+	}
+
+	before, limit, err := parsePageParams(c)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	chatType := model.TypeCH
+	messages, err := ch.messageRepo.GetPage(c.Request().Context(), model.MessageInterface{
+		ChatID: &channelid,
+		Type:   &chatType,
+	}, before, limit)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	messageIDs := make([]uint64, len(messages))
+	for i, message := range messages {
+		messageIDs[i] = message.MessageID
+	}
+
+	if err = ch.viewRepo.Record(c.Request().Context(), userid, messageIDs); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	views, err := ch.viewRepo.Count(c.Request().Context(), messageIDs)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	type post struct {
+		model.MessageDTO
+		Views int64 `json:"views"`
+	}
+
+	posts := make([]post, len(messages))
+	for i, message := range messages {
+		posts[i] = post{
+			MessageDTO: message,
+			Views:      views[message.MessageID],
+		}
+	}
+
+	return c.JSON(http.StatusOK, posts)
+}
+
+func (ch *Channel) NewChannelHandler(g *echo.Group) {
+	channelGroup := g.Group("/channels")
+
+	channelGroup.POST("", ch.NewChannel, mwares.JWTMiddleware)
+	channelGroup.GET("", ch.GetChannels, mwares.JWTMiddleware)
+	channelGroup.GET("/handle/:handle", ch.GetChannelByHandle, mwares.JWTMiddleware)
+	channelGroup.GET("/:channelid", ch.GetChannel, mwares.JWTMiddleware)
+	channelGroup.PATCH("/:channelid", ch.UpdateChannel, mwares.JWTMiddleware)
+	channelGroup.DELETE("/:channelid", ch.DeleteChannel, mwares.JWTMiddleware)
+	channelGroup.POST("/:channelid/subscribe", ch.Subscribe, mwares.JWTMiddleware)
+	channelGroup.DELETE("/:channelid/subscribe", ch.Unsubscribe, mwares.JWTMiddleware)
+	channelGroup.PUT("/:channelid/admins/:userid", ch.SetAdmin, mwares.JWTMiddleware)
+	channelGroup.DELETE("/:channelid/admins/:userid", ch.SetAdmin, mwares.JWTMiddleware)
+	channelGroup.POST("/:channelid/message", ch.NewChannelMessage, mwares.JWTMiddleware)
+	channelGroup.GET("/:channelid/message", ch.GetChannelMessages, mwares.JWTMiddleware)
+	channelGroup.DELETE("/:channelid/message/:messageid", ch.DeleteChannelMessage, mwares.JWTMiddleware)
+}
@@ -1,8 +1,14 @@
 package endpoints
 
 import (
+	"backend/internal/blocking"
+	"backend/internal/events"
+	"backend/internal/flood"
+	"backend/internal/folders"
 	"backend/internal/model"
 	"backend/internal/mwares"
+	"encoding/json"
+	"errors"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
 	"net/http"
@@ -12,13 +18,26 @@ import (
 
 type Chat struct {
 	repo        model.UserChatRepository
+	userRepo    model.UserRepository
 	messageRepo model.MessageRepository
+	settingRepo model.ConversationSettingRepository
+	folders     *folders.Evaluator
+	limiter     *flood.Limiter
+	blocks      *blocking.Service
+	hub         *events.Hub
 }
 
-func NewUserChat(repo model.UserChatRepository, messageRepo model.MessageRepository) *Chat {
+func NewUserChat(repo model.UserChatRepository, userRepo model.UserRepository, messageRepo model.MessageRepository, settingRepo model.ConversationSettingRepository,
+	folders *folders.Evaluator, limiter *flood.Limiter, blocks *blocking.Service, hub *events.Hub) *Chat {
 	return &Chat{
+		limiter:     limiter,
+		blocks:      blocks,
+		settingRepo: settingRepo,
 		messageRepo: messageRepo,
+		userRepo:    userRepo,
+		folders:     folders,
 		repo:        repo,
+		hub:         hub,
 	}
 }
 
@@ -28,41 +47,73 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-func (ch *Chat) NewChat(c echo.Context) error {
-	id := c.Get("userID")
-	userid, _ := id.(uint64)
-
-	reciverid, err := strconv.ParseUint(c.FormValue("id"), 10, 64)
-	if err != nil {
-		return echo.ErrBadRequest
+// openChat returns the private chat between userid and peerid, creating it
+// on first use. Both directions of a pair share the same chat.
+func (ch *Chat) openChat(c echo.Context, userid, peerid uint64) error {
+	if userid == peerid {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "can not open a chat with yourself",
+		})
 	}
 
-	chats, err := ch.repo.Get(c.Request().Context(), model.ChatInterface{
-		UserID:     &userid,
-		ReceiverID: &reciverid,
+	users, err := ch.userRepo.Get(c.Request().Context(), model.UserInterface{
+		ID: &peerid,
 	})
 	if err != nil {
 		return echo.ErrInternalServerError
 	}
-
-	if len(chats) != 0 {
-		return c.JSON(http.StatusCreated, map[string]string{
-			"msg": "this chat already exists",
+	if len(users) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "user not found",
 		})
 	}
 
-	if err := ch.repo.Create(c.Request().Context(), model.Chat{
-		UserID:     userid,
-		ReceiverID: reciverid,
-	}); err != nil {
+	if err = checkBlocked(c, ch.blocks, userid, peerid); err != nil {
+		return err
+	}
+
+	chat, created, err := ch.repo.Open(c.Request().Context(), userid, peerid)
+	if err != nil {
 		return echo.ErrInternalServerError
 	}
 
-	return c.JSON(http.StatusCreated, map[string]string{
-		"msg": "chat created",
+	if !created {
+		return c.JSON(http.StatusOK, echo.Map{
+			"msg":  "this chat already exists",
+			"chat": chat,
+		})
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"msg":  "chat created",
+		"chat": chat,
 	})
 }
 
+func (ch *Chat) NewChat(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	reciverid, err := strconv.ParseUint(c.FormValue("id"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	return ch.openChat(c, userid, reciverid)
+}
+
+func (ch *Chat) OpenChatWithUser(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	peerid, err := strconv.ParseUint(c.Param("userid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	return ch.openChat(c, userid, peerid)
+}
+
 func (ch *Chat) GetChat(c echo.Context) error {
 	chatid, err := strconv.ParseUint(c.Param("chatid"), 10, 64)
 	if err != nil {
@@ -83,46 +134,111 @@ func (ch *Chat) GetChat(c echo.Context) error {
 	return c.JSON(http.StatusOK, chats)
 }
 
-func (ch *Chat) GetChats(c echo.Context) error {
-	id := c.Get("userID")
-	userid, _ := id.(uint64)
+type chatListItem struct {
+	Chat          model.Chat                `json:"chat"`
+	UnreadMessage int                       `json:"unreadMessage"`
+	Settings      model.ConversationSetting `json:"settings"`
+}
 
+// listChats returns the user's private chats with unread counts and their
+// per-user settings. Archived chats are listed only when archived is true,
+// and the others only when it is false; nil lists both. Pinned chats come
+// first in pin order.
+func (ch *Chat) listChats(c echo.Context, userid uint64, archived *bool) ([]chatListItem, error) {
 	chats, err := ch.repo.Get(c.Request().Context(), model.ChatInterface{
 		UserID: &userid,
 	})
 	if err != nil {
-		return echo.ErrInternalServerError
+		return nil, err
 	}
 
 	reciverChats, err := ch.repo.Get(c.Request().Context(), model.ChatInterface{
 		ReceiverID: &userid,
 	})
 	if err != nil {
-		return echo.ErrInternalServerError
+		return nil, err
 	}
 
 	allChats := append(chats, reciverChats...)
 
-	type response struct {
-		Chat          model.Chat `json:"chat"`
-		UnreadMessage int        `json:"unreadMessage"`
+	settings, err := loadSettings(c, ch.settingRepo, userid)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(allChats) == 0 {
-		return echo.ErrNotFound
+	chatIDs := make([]uint64, len(allChats))
+	for i, v := range allChats {
+		chatIDs[i] = v.ChatID
 	}
 
-	res := make([]response, len(allChats))
-	cond := "false"
-	for i, v := range allChats {
-		messages, _ := ch.messageRepo.Get(c.Request().Context(), model.MessageInterface{
-			IsRead: &cond,
-			ChatID: &v.ChatID,
-		})
-		res[i] = response{
+	counts, err := ch.messageRepo.CountUnread(c.Request().Context(), userid, model.TypePV, chatIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]chatListItem, 0, len(allChats))
+	for _, v := range allChats {
+		setting := settings[folders.Key{Type: model.TypePV, ChatID: v.ChatID}]
+		if archived != nil && setting.Archived != *archived {
+			continue
+		}
+
+		unread := int(counts[v.ChatID])
+		if setting.MarkedUnread && unread == 0 {
+			unread = 1
+		}
+
+		res = append(res, chatListItem{
 			Chat:          v,
-			UnreadMessage: len(messages),
+			UnreadMessage: unread,
+			Settings:      setting,
+		})
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		pi, pj := res[i].Settings.PinOrder, res[j].Settings.PinOrder
+		if pi == 0 || pj == 0 {
+			return pi != 0 && pj == 0
 		}
+		return pi < pj
+	})
+
+	return res, nil
+}
+
+func (ch *Chat) GetChats(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	matched, err := folderFilter(c, ch.folders, userid)
+	if err != nil {
+		return err
+	}
+
+	// A folder decides for itself which archived chats it shows.
+	archived := c.QueryParam("archived") == "true"
+	listArchived := &archived
+	if matched != nil {
+		listArchived = nil
+	}
+
+	res, err := ch.listChats(c, userid, listArchived)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if matched != nil {
+		inFolder := res[:0]
+		for _, item := range res {
+			if matched[folders.Key{Type: model.TypePV, ChatID: item.Chat.ChatID}] {
+				inFolder = append(inFolder, item)
+			}
+		}
+		res = inFolder
+	}
+
+	if len(res) == 0 {
+		return echo.ErrNotFound
 	}
 
 	return c.JSON(http.StatusOK, res)
@@ -139,44 +255,16 @@ func (ch *Chat) GetChatsSocket(c echo.Context) error {
 	userid, _ := id.(uint64)
 
 	sendUpdatedChats := func() error {
-		chats, err := ch.repo.Get(c.Request().Context(), model.ChatInterface{
-			UserID: &userid,
-		})
+		archived := false
+		res, err := ch.listChats(c, userid, &archived)
 		if err != nil {
 			return echo.ErrInternalServerError
 		}
 
-		receiverChats, err := ch.repo.Get(c.Request().Context(), model.ChatInterface{
-			ReceiverID: &userid,
-		})
-		if err != nil {
-			return echo.ErrInternalServerError
-		}
-
-		allChats := append(chats, receiverChats...)
-
-		type response struct {
-			Chat          model.Chat `json:"chat"`
-			UnreadMessage int        `json:"unreadMessage"`
-		}
-
-		if len(allChats) == 0 {
+		if len(res) == 0 {
 			return nil
 		}
 
-		res := make([]response, len(allChats))
-		cond := "false"
-		for i, v := range allChats {
-			messages, _ := ch.messageRepo.Get(c.Request().Context(), model.MessageInterface{
-				IsRead: &cond,
-				ChatID: &v.ChatID,
-			})
-			res[i] = response{
-				Chat:          v,
-				UnreadMessage: len(messages),
-			}
-		}
-
 		return ws.WriteJSON(res)
 	}
 
@@ -283,7 +371,8 @@ func (ch *Chat) DeleteChatMessage(c echo.Context) error {
 	}
 
 	if err := ch.messageRepo.Delete(c.Request().Context(), model.MessageInterface{
-		ID: &messageid,
+		ID:             &messageid,
+		ConversationID: &chats[0].ConversationID,
 	}); err != nil {
 		return echo.ErrInternalServerError
 	}
@@ -319,21 +408,34 @@ func (ch *Chat) NewChatMessage(c echo.Context) error {
 			"msg": "can not access this chat",
 		})
 	}
+	if err = checkBlocked(c, ch.blocks, senderid, chats[0].Peer(senderid)); err != nil {
+		return err
+	}
 
 	messageContent := c.FormValue("content")
-	if messageContent == "" {
+	payload := json.RawMessage(c.FormValue("payload"))
+	kind, err := model.ValidateUserMessage(model.ContentKind(c.FormValue("kind")), messageContent, payload)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
-			"msg": "message content can not be empty",
+			"msg": err.Error(),
 		})
 	}
 
+	conv := flood.Conversation{Type: model.TypePV, ChatID: chatID}
+	if refused := ch.limiter.Allow(senderid, conv, 0, kind, messageContent, payload); refused != nil {
+		return floodResponse(c, refused)
+	}
+
 	if _, err := ch.messageRepo.Create(c.Request().Context(), model.Message{
 		ChatID:   chatID,
 		SenderID: senderid,
 		Type:     model.TypePV,
+		Kind:     kind,
 		IsRead:   "false",
 		Content:  messageContent,
+		Payload:  payload,
 	}); err != nil {
+		ch.limiter.Undo(senderid, conv, kind, messageContent, payload)
 		return echo.ErrInternalServerError
 	}
 
@@ -359,8 +461,10 @@ func (ch *Chat) NewChatMessageWs(c echo.Context) error {
 
 	for {
 		var incomingMessage struct {
-			Content string `json:"content"`
-			Stat    string `json:"stat"`
+			Content string            `json:"content"`
+			Kind    model.ContentKind `json:"kind"`
+			Payload json.RawMessage   `json:"payload"`
+			Stat    string            `json:"stat"`
 		}
 
 		err = ws.ReadJSON(&incomingMessage)
@@ -393,9 +497,23 @@ func (ch *Chat) NewChatMessageWs(c echo.Context) error {
 			ws.WriteMessage(websocket.TextMessage, []byte("Cannot access this chat"))
 			continue
 		}
+		if err = ch.blocks.Check(c.Request().Context(), senderid, chats[0].Peer(senderid)); err != nil {
+			if !errors.Is(err, blocking.ErrBlocked) {
+				return echo.ErrInternalServerError
+			}
+			ws.WriteMessage(websocket.TextMessage, []byte(err.Error()))
+			continue
+		}
+
+		kind, err := model.ValidateUserMessage(incomingMessage.Kind, messageContent, incomingMessage.Payload)
+		if err != nil {
+			ws.WriteMessage(websocket.TextMessage, []byte(err.Error()))
+			continue
+		}
 
-		if messageContent == "" {
-			ws.WriteMessage(websocket.TextMessage, []byte("Message content cannot be empty"))
+		conv := flood.Conversation{Type: model.TypePV, ChatID: chatID}
+		if refused := ch.limiter.Allow(senderid, conv, 0, kind, messageContent, incomingMessage.Payload); refused != nil {
+			ws.WriteJSON(floodBody(refused))
 			continue
 		}
 
@@ -403,9 +521,12 @@ func (ch *Chat) NewChatMessageWs(c echo.Context) error {
 			ChatID:   chatID,
 			SenderID: senderid,
 			Type:     model.TypePV,
+			Kind:     kind,
 			IsRead:   "false",
 			Content:  messageContent,
+			Payload:  incomingMessage.Payload,
 		}); err != nil {
+			ch.limiter.Undo(senderid, conv, kind, messageContent, incomingMessage.Payload)
 			return echo.ErrInternalServerError
 		}
 
@@ -465,6 +586,10 @@ func (ch *Chat) GetMessageByCount(c echo.Context) error {
 		count = uint64(len(messages))
 	}
 
+	if err = clearMarkedUnread(c, ch.settingRepo, ch.hub, userid, model.TypePV, chatid); err != nil {
+		return echo.ErrInternalServerError
+	}
+
 	return c.JSON(http.StatusOK, messages[:count])
 }
 
@@ -538,6 +663,10 @@ func (ch *Chat) GetMessageByCountWs(c echo.Context) error {
 			count = uint64(len(messages))
 		}
 
+		if err = clearMarkedUnread(c, ch.settingRepo, ch.hub, userid, model.TypePV, chatid); err != nil {
+			return echo.ErrInternalServerError
+		}
+
 		err = ws.WriteJSON(messages[:count])
 		if err != nil {
 			return err
@@ -551,6 +680,7 @@ func (ch *Chat) NewUserChatHandler(g *echo.Group) {
 	chatGroup := g.Group("/chats")
 
 	chatGroup.POST("", ch.NewChat, mwares.JWTMiddleware)
+	chatGroup.POST("/with/:userid", ch.OpenChatWithUser, mwares.JWTMiddleware)
 	chatGroup.GET("", ch.GetChats, mwares.JWTMiddleware)
 	chatGroup.GET("/ws", ch.GetChatsSocket, mwares.JWTMiddleware)
 	chatGroup.GET("/:chatid", ch.GetChat, mwares.JWTMiddleware)
@@ -0,0 +1,914 @@
+package endpoints
+
+import (
+	"backend/internal/membership"
+	"backend/internal/model"
+	"backend/internal/mwares"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type Community struct {
+	repo            model.CommunityRepository
+	groupRepo       model.GroupRepository
+	userGroupRepo   model.UserGroupRepository
+	channelRepo     model.ChannelRepository
+	userChannelRepo model.UserChannelRepository
+	membership      *membership.Service
+	groups          *Group
+}
+
+func NewCommunity(repo model.CommunityRepository, groupRepo model.GroupRepository, userGroupRepo model.UserGroupRepository,
+	channelRepo model.ChannelRepository, userChannelRepo model.UserChannelRepository, membership *membership.Service,
+	groups *Group) *Community {
+	return &Community{
+		groups:          groups,
+		membership:      membership,
+		userChannelRepo: userChannelRepo,
+		channelRepo:     channelRepo,
+		userGroupRepo:   userGroupRepo,
+		groupRepo:       groupRepo,
+		repo:            repo,
+	}
+}
+
+// announcementAdmin is what community admins may do in the announcement
+// group; everyone else there is read-only.
+var announcementAdmin = model.GroupPermissions{
+	CanDeleteMessages: true,
+	CanPinMessages:    true,
+}
+
+func (co *Community) community(c echo.Context) (*model.Community, error) {
+	communityID, err := strconv.ParseUint(c.Param("communityid"), 10, 64)
+	if err != nil {
+		return nil, echo.ErrBadRequest
+	}
+
+	communities, err := co.repo.Get(c.Request().Context(), model.CommunityInterface{
+		ID: &communityID,
+	})
+	if err != nil {
+		return nil, echo.ErrInternalServerError
+	}
+	if len(communities) == 0 {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "community not found")
+	}
+
+	return &communities[0], nil
+}
+
+// member returns userID's membership of communityID, or nil when they are
+// not in it.
+func (co *Community) member(ctx context.Context, communityID, userID uint64) (*model.UserCommunityDTO, error) {
+	members, err := co.repo.GetMembers(ctx, model.UserCommunityInterface{
+		CommunityID: &communityID,
+		UserID:      &userID,
+	})
+	if err != nil || len(members) == 0 {
+		return nil, err
+	}
+
+	return &members[0], nil
+}
+
+// manager loads the community and fails unless the caller may manage it.
+func (co *Community) manager(c echo.Context, userID uint64) (*model.Community, error) {
+	community, err := co.community(c)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := co.member(c.Request().Context(), community.CommunityID, userID)
+	if err != nil {
+		return nil, echo.ErrInternalServerError
+	}
+	if member == nil || !member.Role.CanManage() {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "only community admins can do this")
+	}
+
+	return community, nil
+}
+
+// joinGroup adds userID to groupID with role on behalf of communityID
+// unless they are already in it or banned from it, and reports whether they
+// were added.
+func (co *Community) joinGroup(ctx context.Context, communityID, groupID, userID uint64, role model.GroupRole,
+	permissions model.GroupPermissions) (bool, error) {
+	existing, err := co.userGroupRepo.Get(ctx, model.UserGroupInterface{
+		GroupID: &groupID,
+		UserID:  &userID,
+	})
+	if err != nil || len(existing) != 0 {
+		return false, err
+	}
+
+	if _, err = co.membership.CheckBan(ctx, groupID, userID); errors.Is(err, membership.ErrBanned) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if err = co.userGroupRepo.Create(ctx, model.UserGroup{
+		GroupID:          groupID,
+		UserID:           userID,
+		Role:             role,
+		CommunityID:      communityID,
+		GroupPermissions: permissions,
+	}); err != nil {
+		return false, err
+	}
+
+	return true, co.membership.Joined(ctx, groupID, userID, userID)
+}
+
+// joinChannel subscribes userID to channelID on behalf of communityID
+// unless they already are.
+func (co *Community) joinChannel(ctx context.Context, communityID, channelID, userID uint64) (bool, error) {
+	existing, err := co.userChannelRepo.Get(ctx, model.UserChannelInterface{
+		ChannelID: &channelID,
+		UserID:    &userID,
+	})
+	if err != nil || len(existing) != 0 {
+		return false, err
+	}
+
+	return true, co.userChannelRepo.Create(ctx, model.UserChannel{
+		ChannelID:   channelID,
+		UserID:      userID,
+		Role:        model.ChannelSubscriber,
+		CommunityID: communityID,
+	})
+}
+
+func (co *Community) joinLink(ctx context.Context, link model.CommunityLink, userID uint64) (bool, error) {
+	if link.Type == model.TypeCH {
+		return co.joinChannel(ctx, link.CommunityID, link.ChatID, userID)
+	}
+
+	return co.joinGroup(ctx, link.CommunityID, link.ChatID, userID, model.GroupMember, model.GroupPermissions{})
+}
+
+// requestLinkedGroup files a join request for userID to a linked group that
+// requires approval, unless they are already in it or banned from it, and
+// reports whether a request is now pending.
+func (co *Community) requestLinkedGroup(c echo.Context, groupID, userID uint64) (bool, error) {
+	ctx := c.Request().Context()
+	existing, err := co.userGroupRepo.Get(ctx, model.UserGroupInterface{
+		GroupID: &groupID,
+		UserID:  &userID,
+	})
+	if err != nil || len(existing) != 0 {
+		return false, err
+	}
+
+	if _, err = co.membership.CheckBan(ctx, groupID, userID); errors.Is(err, membership.ErrBanned) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if _, _, err = co.groups.fileJoinRequest(c, groupID, userID, "", 0); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// joinDerived gives a new community member everything membership implies:
+// the announcement group and every linked group or channel with auto-join.
+// Linked groups that require approval get a join request instead; their
+// ids are returned.
+func (co *Community) joinDerived(c echo.Context, community model.Community, userID uint64) ([]uint64, error) {
+	ctx := c.Request().Context()
+	if _, err := co.joinGroup(ctx, community.CommunityID, community.AnnouncementGroupID, userID, model.GroupRestricted,
+		model.GroupPermissions{}); err != nil {
+		return nil, err
+	}
+
+	links, err := co.repo.GetLinks(ctx, model.CommunityLinkInterface{
+		CommunityID: &community.CommunityID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	requested := []uint64{}
+	for _, link := range links {
+		if !link.AutoJoin {
+			continue
+		}
+
+		if link.Type == model.TypeGP {
+			groups, err := co.groupRepo.Get(ctx, model.GroupInterface{
+				ID: &link.ChatID,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if len(groups) == 0 {
+				continue
+			}
+			if groups[0].RequiresApproval {
+				pending, err := co.requestLinkedGroup(c, link.ChatID, userID)
+				if err != nil {
+					return nil, err
+				}
+				if pending {
+					requested = append(requested, link.ChatID)
+				}
+				continue
+			}
+		}
+
+		if _, err = co.joinLink(ctx, link, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return requested, nil
+}
+
+// leaveDerived undoes joinDerived: it only removes memberships the
+// community itself created. Linked groups where the user has become owner
+// or admin, and channels they run, are left alone.
+func (co *Community) leaveDerived(ctx context.Context, community model.Community, userID uint64) error {
+	links, err := co.repo.GetLinks(ctx, model.CommunityLinkInterface{
+		CommunityID: &community.CommunityID,
+	})
+	if err != nil {
+		return err
+	}
+
+	groups := []uint64{community.AnnouncementGroupID}
+	for _, link := range links {
+		if !link.AutoJoin {
+			continue
+		}
+		if link.Type == model.TypeGP {
+			groups = append(groups, link.ChatID)
+			continue
+		}
+
+		subs, err := co.userChannelRepo.Get(ctx, model.UserChannelInterface{
+			ChannelID: &link.ChatID,
+			UserID:    &userID,
+		})
+		if err != nil {
+			return err
+		}
+		if len(subs) == 0 || subs[0].CommunityID != community.CommunityID || subs[0].Role != model.ChannelSubscriber {
+			continue
+		}
+		if err = co.userChannelRepo.Delete(ctx, model.UserChannelInterface{
+			ChannelID: &link.ChatID,
+			UserID:    &userID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, groupID := range groups {
+		members, err := co.userGroupRepo.Get(ctx, model.UserGroupInterface{
+			GroupID: &groupID,
+			UserID:  &userID,
+		})
+		if err != nil {
+			return err
+		}
+		if len(members) == 0 || members[0].Role == model.GroupOwner {
+			continue
+		}
+		// Membership of the announcement group, admin rights included, only
+		// ever comes from the community, so it always goes with it.
+		if groupID != community.AnnouncementGroupID &&
+			(members[0].CommunityID != community.CommunityID || members[0].Role == model.GroupAdmin) {
+			continue
+		}
+
+		if _, err = co.membership.Leave(ctx, groupID, userID); err != nil && !errors.Is(err, membership.ErrNotMember) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (co *Community) NewCommunity(c echo.Context) error {
+	id := c.Get("userID")
+	ownerid, _ := id.(uint64)
+
+	name := c.FormValue("name")
+	if name == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "community name can not be empty",
+		})
+	}
+	description := c.FormValue("description")
+
+	ctx := c.Request().Context()
+	communityID, groupID, err := co.repo.Create(ctx, model.Community{
+		Name:        name,
+		Description: description,
+		Owner:       ownerid,
+	}, model.Group{
+		Creator:     ownerid,
+		Name:        name,
+		Description: description,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if err = co.membership.Announce(ctx, groupID, model.SystemPayload{
+		Event:   model.SystemGroupCreated,
+		ActorID: ownerid,
+		New:     name,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"msg":                 "community created",
+		"communityID":         communityID,
+		"announcementGroupID": groupID,
+	})
+}
+
+func (co *Community) GetCommunities(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	communities, err := co.repo.GetForUser(c.Request().Context(), userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, communities)
+}
+
+func (co *Community) GetCommunity(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	community, err := co.community(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	count, err := co.repo.CountMembers(ctx, community.CommunityID)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	member, err := co.member(ctx, community.CommunityID, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	res := echo.Map{
+		"community": community,
+		"members":   count,
+	}
+	if member != nil {
+		res["role"] = member.Role
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func (co *Community) UpdateCommunity(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	community, err := co.manager(c, userid)
+	if err != nil {
+		return err
+	}
+
+	if v := c.FormValue("name"); v != "" {
+		community.Name = v
+	}
+	if v := c.FormValue("description"); v != "" {
+		community.Description = v
+	}
+
+	if err = co.repo.Update(c.Request().Context(), *community); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":       "community updated",
+		"community": community,
+	})
+}
+
+func (co *Community) DeleteCommunity(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	community, err := co.community(c)
+	if err != nil {
+		return err
+	}
+	if community.Owner != userid {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"msg": "only the owner can delete a community",
+		})
+	}
+
+	if err = co.repo.Delete(c.Request().Context(), community.CommunityID); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "community deleted",
+	})
+}
+
+func (co *Community) JoinCommunity(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	community, err := co.community(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	member, err := co.member(ctx, community.CommunityID, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if member != nil {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"msg": "you are already a member of this community",
+		})
+	}
+
+	if err = co.repo.AddMember(ctx, model.UserCommunity{
+		CommunityID: community.CommunityID,
+		UserID:      userid,
+		Role:        model.CommunityMember,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	requested, err := co.joinDerived(c, *community, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":             "joined community",
+		"pendingApproval": requested,
+	})
+}
+
+func (co *Community) LeaveCommunity(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	community, err := co.community(c)
+	if err != nil {
+		return err
+	}
+	if community.Owner == userid {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"msg": "the owner can not leave a community; delete it instead",
+		})
+	}
+
+	ctx := c.Request().Context()
+	member, err := co.member(ctx, community.CommunityID, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if member == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "you are not part of this community",
+		})
+	}
+
+	if err = co.repo.RemoveMember(ctx, community.CommunityID, userid); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if err = co.leaveDerived(ctx, *community, userid); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "left community",
+	})
+}
+
+// SetCommunityAdmin promotes a member to community admin (PUT) or demotes
+// them (DELETE). Community admins can post in the announcement group.
+func (co *Community) SetCommunityAdmin(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	community, err := co.community(c)
+	if err != nil {
+		return err
+	}
+	if community.Owner != userid {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"msg": "only the owner can manage community admins",
+		})
+	}
+
+	targetID, err := strconv.ParseUint(c.Param("userid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	if targetID == userid {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "the owner's role can not be changed",
+		})
+	}
+
+	ctx := c.Request().Context()
+	member, err := co.member(ctx, community.CommunityID, targetID)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if member == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "user is not a member of this community",
+		})
+	}
+
+	role, groupRole, permissions := model.CommunityAdmin, model.GroupAdmin, announcementAdmin
+	if c.Request().Method == http.MethodDelete {
+		role, groupRole, permissions = model.CommunityMember, model.GroupRestricted, model.GroupPermissions{}
+	}
+
+	if err = co.repo.SetMemberRole(ctx, community.CommunityID, targetID, role); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	added, err := co.joinGroup(ctx, community.CommunityID, community.AnnouncementGroupID, targetID, groupRole, permissions)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if !added {
+		if err = co.userGroupRepo.SetRole(ctx, model.UserGroup{
+			GroupID:          community.AnnouncementGroupID,
+			UserID:           targetID,
+			Role:             groupRole,
+			GroupPermissions: permissions,
+		}); err != nil {
+			return echo.ErrInternalServerError
+		}
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":  "role updated",
+		"role": role,
+	})
+}
+
+// canLink reports whether userID runs the group or channel well enough to
+// put it in a community.
+func (co *Community) canLink(ctx context.Context, chatType model.MessageType, chatID, userID uint64) (bool, error) {
+	if chatType == model.TypeCH {
+		subs, err := co.userChannelRepo.Get(ctx, model.UserChannelInterface{
+			ChannelID: &chatID,
+			UserID:    &userID,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		return len(subs) != 0 && subs[0].Role.CanPost(), nil
+	}
+
+	members, err := co.userGroupRepo.Get(ctx, model.UserGroupInterface{
+		GroupID: &chatID,
+		UserID:  &userID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(members) != 0 && members[0].Can(model.GroupEditInfo), nil
+}
+
+func linkParams(c echo.Context, typeValue, chatValue string) (model.MessageType, uint64, error) {
+	chatType := model.MessageType(strings.ToUpper(typeValue))
+	if chatType != model.TypeGP && chatType != model.TypeCH {
+		return "", 0, echo.NewHTTPError(http.StatusBadRequest, "type must be gp or ch")
+	}
+
+	chatID, err := strconv.ParseUint(chatValue, 10, 64)
+	if err != nil {
+		return "", 0, echo.ErrBadRequest
+	}
+
+	return chatType, chatID, nil
+}
+
+// LinkToCommunity puts a group or channel the caller runs into the
+// community. With autojoin, every current and future member is added.
+func (co *Community) LinkToCommunity(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	community, err := co.manager(c, userid)
+	if err != nil {
+		return err
+	}
+
+	chatType, chatID, err := linkParams(c, c.FormValue("type"), c.FormValue("chatid"))
+	if err != nil {
+		return err
+	}
+	if chatType == model.TypeGP && chatID == community.AnnouncementGroupID {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "the announcement group is already part of the community",
+		})
+	}
+
+	autoJoin, err := formBool(c, "autojoin", false)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ctx := c.Request().Context()
+	ok, err := co.canLink(ctx, chatType, chatID, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if !ok {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"msg": "you can only link groups and channels you administer",
+		})
+	}
+
+	linked, err := co.repo.GetLinks(ctx, model.CommunityLinkInterface{
+		Type:   &chatType,
+		ChatID: &chatID,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if len(linked) != 0 {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"msg": "this conversation already belongs to a community",
+		})
+	}
+
+	link := model.CommunityLink{
+		CommunityID: community.CommunityID,
+		Type:        chatType,
+		ChatID:      chatID,
+		AutoJoin:    autoJoin,
+	}
+	if err = co.repo.Link(ctx, link); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if autoJoin {
+		members, err := co.repo.GetMembers(ctx, model.UserCommunityInterface{
+			CommunityID: &community.CommunityID,
+		})
+		if err != nil {
+			return echo.ErrInternalServerError
+		}
+
+		for _, member := range members {
+			if _, err = co.joinLink(ctx, link, member.UserID); err != nil {
+				return echo.ErrInternalServerError
+			}
+		}
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"msg":  "linked",
+		"link": link,
+	})
+}
+
+func (co *Community) UnlinkFromCommunity(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	community, err := co.manager(c, userid)
+	if err != nil {
+		return err
+	}
+
+	chatType, chatID, err := linkParams(c, c.Param("type"), c.Param("chatid"))
+	if err != nil {
+		return err
+	}
+
+	if err = co.repo.Unlink(c.Request().Context(), community.CommunityID, chatType, chatID); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "unlinked",
+	})
+}
+
+// GetCommunityLinks lets members browse the community's groups and channels
+// with their names and whether they are already in each.
+func (co *Community) GetCommunityLinks(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	community, err := co.community(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	member, err := co.member(ctx, community.CommunityID, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if member == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "you are not part of this community",
+		})
+	}
+
+	links, err := co.repo.GetLinks(ctx, model.CommunityLinkInterface{
+		CommunityID: &community.CommunityID,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	res := make([]echo.Map, 0, len(links))
+	for _, link := range links {
+		item := echo.Map{
+			"type":     link.Type,
+			"chatID":   link.ChatID,
+			"autoJoin": link.AutoJoin,
+		}
+
+		if link.Type == model.TypeCH {
+			channels, err := co.channelRepo.Get(ctx, model.ChannelInterface{
+				ID: &link.ChatID,
+			})
+			if err != nil {
+				return echo.ErrInternalServerError
+			}
+			if len(channels) == 0 {
+				continue
+			}
+			subs, err := co.userChannelRepo.Get(ctx, model.UserChannelInterface{
+				ChannelID: &link.ChatID,
+				UserID:    &userid,
+			})
+			if err != nil {
+				return echo.ErrInternalServerError
+			}
+			item["name"] = channels[0].Name
+			item["description"] = channels[0].Description
+			item["joined"] = len(subs) != 0
+		} else {
+			groups, err := co.groupRepo.Get(ctx, model.GroupInterface{
+				ID: &link.ChatID,
+			})
+			if err != nil {
+				return echo.ErrInternalServerError
+			}
+			if len(groups) == 0 {
+				continue
+			}
+			members, err := co.userGroupRepo.Get(ctx, model.UserGroupInterface{
+				GroupID: &link.ChatID,
+				UserID:  &userid,
+			})
+			if err != nil {
+				return echo.ErrInternalServerError
+			}
+			item["name"] = groups[0].Name
+			item["description"] = groups[0].Description
+			item["avatar"] = groups[0].Avatar
+			item["requiresApproval"] = groups[0].RequiresApproval
+			item["joined"] = len(members) != 0
+		}
+
+		res = append(res, item)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"announcementGroupID": community.AnnouncementGroupID,
+		"links":               res,
+	})
+}
+
+// JoinLinked joins one group or channel of the community. Groups that
+// require approval still have to be asked through a join request.
+func (co *Community) JoinLinked(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	community, err := co.community(c)
+	if err != nil {
+		return err
+	}
+
+	chatType, chatID, err := linkParams(c, c.Param("type"), c.Param("chatid"))
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	member, err := co.member(ctx, community.CommunityID, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if member == nil {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"msg": "join the community first",
+		})
+	}
+
+	links, err := co.repo.GetLinks(ctx, model.CommunityLinkInterface{
+		CommunityID: &community.CommunityID,
+		Type:        &chatType,
+		ChatID:      &chatID,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if len(links) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "this conversation is not part of the community",
+		})
+	}
+
+	if chatType == model.TypeGP {
+		groups, err := co.groupRepo.Get(ctx, model.GroupInterface{
+			ID: &chatID,
+		})
+		if err != nil {
+			return echo.ErrInternalServerError
+		}
+		if len(groups) == 0 {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"msg": "group not found",
+			})
+		}
+		if groups[0].RequiresApproval {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"msg": "this group requires approval; send a join request",
+			})
+		}
+
+		ban, err := co.membership.CheckBan(ctx, chatID, userid)
+		if errors.Is(err, membership.ErrBanned) {
+			return c.JSON(http.StatusForbidden, echo.Map{
+				"msg":       "user is banned from this group",
+				"expiresAt": ban.ExpiresAt,
+			})
+		}
+		if err != nil {
+			return echo.ErrInternalServerError
+		}
+	}
+
+	joined, err := co.joinLink(ctx, links[0], userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if !joined {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"msg": "you are already a member",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "joined",
+	})
+}
+
+func (co *Community) NewCommunityHandler(gr *echo.Group) {
+	communitiesGroup := gr.Group("/communities")
+
+	communitiesGroup.POST("", co.NewCommunity, mwares.JWTMiddleware)
+	communitiesGroup.GET("", co.GetCommunities, mwares.JWTMiddleware)
+	communitiesGroup.GET("/:communityid", co.GetCommunity, mwares.JWTMiddleware)
+	communitiesGroup.PATCH("/:communityid", co.UpdateCommunity, mwares.JWTMiddleware)
+	communitiesGroup.DELETE("/:communityid", co.DeleteCommunity, mwares.JWTMiddleware)
+	communitiesGroup.POST("/:communityid/join", co.JoinCommunity, mwares.JWTMiddleware)
+	communitiesGroup.POST("/:communityid/leave", co.LeaveCommunity, mwares.JWTMiddleware)
+	communitiesGroup.PUT("/:communityid/admins/:userid", co.SetCommunityAdmin, mwares.JWTMiddleware)
+	communitiesGroup.DELETE("/:communityid/admins/:userid", co.SetCommunityAdmin, mwares.JWTMiddleware)
+	communitiesGroup.GET("/:communityid/links", co.GetCommunityLinks, mwares.JWTMiddleware)
+	communitiesGroup.POST("/:communityid/links", co.LinkToCommunity, mwares.JWTMiddleware)
+	communitiesGroup.DELETE("/:communityid/links/:type/:chatid", co.UnlinkFromCommunity, mwares.JWTMiddleware)
+	communitiesGroup.POST("/:communityid/links/:type/:chatid/join", co.JoinLinked, mwares.JWTMiddleware)
+}
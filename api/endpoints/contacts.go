@@ -0,0 +1,349 @@
+package endpoints
+
+import (
+	"backend/internal/model"
+	"context"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+)
+
+const EventContactRequest = "contact.request"
+
+// Actions carried by contact.request events.
+const (
+	ContactRequested = "requested"
+	ContactAccepted  = "accepted"
+	ContactDeclined  = "declined"
+	ContactCancelled = "cancelled"
+	ContactRemoved   = "removed"
+)
+
+// self loads the user named in the path and fails unless it is the caller,
+// since an account and its contact list can only be changed by its owner.
+func (u *User) self(c echo.Context) (*model.User, error) {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	username := c.Param("username")
+	users, err := u.repo.Get(c.Request().Context(), model.UserInterface{
+		Username: &username,
+	})
+	if err != nil {
+		return nil, echo.ErrInternalServerError
+	}
+	if len(users) == 0 {
+		return nil, echo.ErrNotFound
+	}
+	if users[0].UserID != userid {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "you can only manage your own account")
+	}
+
+	return &users[0], nil
+}
+
+// contact returns userID's row for contactUserID, or nil when there is none.
+func (u *User) contact(ctx context.Context, userID, contactUserID uint64) (*model.Contact, error) {
+	contacts, err := u.contactRepo.Get(ctx, model.ContactInterface{
+		UserID:        &userID,
+		ContactUserID: &contactUserID,
+	})
+	if err != nil || len(contacts) == 0 {
+		return nil, err
+	}
+
+	return &contacts[0], nil
+}
+
+// notifyContact tells both sides of a contact request what happened to it.
+func (u *User) notifyContact(request model.Contact, action string) {
+	u.hub.PublishMany([]uint64{request.UserID, request.ContactUserID}, EventContactRequest, echo.Map{
+		"action":  action,
+		"request": request,
+	})
+}
+
+// accept turns the pending request into mutual contacts.
+func (u *User) accept(ctx context.Context, request model.Contact) error {
+	request.Status = model.Accepted
+	if err := u.contactRepo.Update(ctx, request); err != nil {
+		return err
+	}
+
+	reverse, err := u.contact(ctx, request.ContactUserID, request.UserID)
+	if err != nil {
+		return err
+	}
+	if reverse == nil {
+		err = u.contactRepo.Create(ctx, model.Contact{
+			UserID:        request.ContactUserID,
+			ContactUserID: request.UserID,
+			Status:        model.Accepted,
+		})
+	} else if reverse.Status == model.Pending {
+		reverse.Status = model.Accepted
+		err = u.contactRepo.Update(ctx, *reverse)
+	}
+	if err != nil {
+		return err
+	}
+
+	u.notifyContact(request, ContactAccepted)
+	return nil
+}
+
+func (u *User) GetUserContacts(c echo.Context) error {
+	user, err := u.self(c)
+	if err != nil {
+		return err
+	}
+
+	accepted := model.Accepted
+	contacts, err := u.contactRepo.Get(c.Request().Context(), model.ContactInterface{
+		UserID: &user.UserID,
+		Status: &accepted,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, contacts)
+}
+
+// GetContactRequests lists pending requests sent to the caller and those
+// the caller sent that are still waiting.
+func (u *User) GetContactRequests(c echo.Context) error {
+	user, err := u.self(c)
+	if err != nil {
+		return err
+	}
+
+	pending := model.Pending
+	incoming, err := u.contactRepo.Get(c.Request().Context(), model.ContactInterface{
+		ContactUserID: &user.UserID,
+		Status:        &pending,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	outgoing, err := u.contactRepo.Get(c.Request().Context(), model.ContactInterface{
+		UserID: &user.UserID,
+		Status: &pending,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"incoming": incoming,
+		"outgoing": outgoing,
+	})
+}
+
+// NewUserContact sends a contact request. If the other user already asked
+// the caller, the two requests meet and both become contacts at once.
+func (u *User) NewUserContact(c echo.Context) error {
+	user, err := u.self(c)
+	if err != nil {
+		return err
+	}
+
+	contactUsername := c.FormValue("username")
+	contactUsers, err := u.repo.Get(c.Request().Context(), model.UserInterface{
+		Username: &contactUsername,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if len(contactUsers) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "user not found",
+		})
+	}
+	target := contactUsers[0]
+	if target.UserID == user.UserID {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "you can not add yourself as a contact",
+		})
+	}
+
+	if err = checkBlocked(c, u.blocks, user.UserID, target.UserID); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	oldContact, err := u.contact(ctx, user.UserID, target.UserID)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if oldContact != nil {
+		msg := "contact already exist"
+		if oldContact.Status == model.Pending {
+			msg = "contact request already sent"
+		}
+		return c.JSON(http.StatusConflict, map[string]string{
+			"msg": msg,
+		})
+	}
+
+	incoming, err := u.contact(ctx, target.UserID, user.UserID)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if incoming != nil && incoming.Status == model.Pending {
+		if err = u.accept(ctx, *incoming); err != nil {
+			return echo.ErrInternalServerError
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"msg": "contact request accepted",
+		})
+	}
+
+	if err = u.contactRepo.Create(ctx, model.Contact{
+		UserID:        user.UserID,
+		ContactUserID: target.UserID,
+		Status:        model.Pending,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	request, err := u.contact(ctx, user.UserID, target.UserID)
+	if err != nil || request == nil {
+		return echo.ErrInternalServerError
+	}
+	u.notifyContact(*request, ContactRequested)
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"msg":     "contact request sent",
+		"request": request,
+	})
+}
+
+// pendingFrom parses the requester from the path and returns their pending
+// request to user.
+func (u *User) pendingFrom(c echo.Context, user *model.User) (*model.Contact, error) {
+	requesterID, err := strconv.ParseUint(c.Param("contactid"), 10, 64)
+	if err != nil {
+		return nil, echo.ErrBadRequest
+	}
+
+	request, err := u.contact(c.Request().Context(), requesterID, user.UserID)
+	if err != nil {
+		return nil, echo.ErrInternalServerError
+	}
+	if request == nil || request.Status != model.Pending {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "no pending contact request from this user")
+	}
+
+	return request, nil
+}
+
+// UpdateContact accepts the pending request from the user in the path,
+// making the two of them each other's contacts.
+func (u *User) UpdateContact(c echo.Context) error {
+	user, err := u.self(c)
+	if err != nil {
+		return err
+	}
+
+	request, err := u.pendingFrom(c, user)
+	if err != nil {
+		return err
+	}
+
+	if err = u.accept(c.Request().Context(), *request); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "contact request accepted",
+	})
+}
+
+func (u *User) DeclineContact(c echo.Context) error {
+	user, err := u.self(c)
+	if err != nil {
+		return err
+	}
+
+	request, err := u.pendingFrom(c, user)
+	if err != nil {
+		return err
+	}
+
+	if err = u.contactRepo.Delete(c.Request().Context(), model.ContactInterface{
+		UserID:        &request.UserID,
+		ContactUserID: &request.ContactUserID,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	u.notifyContact(*request, ContactDeclined)
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "contact request declined",
+	})
+}
+
+// DeleteUserContact cancels the caller's pending request to the user in
+// the path, or removes them from each other's contacts.
+func (u *User) DeleteUserContact(c echo.Context) error {
+	user, err := u.self(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseUint(c.Param("contactid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ctx := c.Request().Context()
+	contact, err := u.contact(ctx, user.UserID, id)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if contact == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "contact not found",
+		})
+	}
+	if contact.Status == model.Blocked {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"msg": "this user is blocked; unblock them instead",
+		})
+	}
+
+	if err = u.contactRepo.Delete(ctx, model.ContactInterface{
+		UserID:        &user.UserID,
+		ContactUserID: &id,
+		Status:        &contact.Status,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	switch contact.Status {
+	case model.Pending:
+		u.notifyContact(*contact, ContactCancelled)
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"msg": "contact request cancelled",
+		})
+	case model.Accepted:
+		accepted := model.Accepted
+		if err = u.contactRepo.Delete(ctx, model.ContactInterface{
+			UserID:        &id,
+			ContactUserID: &user.UserID,
+			Status:        &accepted,
+		}); err != nil {
+			return echo.ErrInternalServerError
+		}
+		u.notifyContact(*contact, ContactRemoved)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "deleted contact successfuly",
+	})
+}
@@ -0,0 +1,56 @@
+package endpoints
+
+import (
+	"backend/internal/events"
+	"backend/internal/mwares"
+	"github.com/labstack/echo/v4"
+)
+
+type Events struct {
+	hub *events.Hub
+}
+
+func NewEvents(hub *events.Hub) *Events {
+	return &Events{
+		hub: hub,
+	}
+}
+
+func (e *Events) GetEventsWs(c echo.Context) error {
+	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	ch := e.hub.Subscribe(userid)
+	defer e.hub.Unsubscribe(userid, ch)
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case event := <-ch:
+			if err = ws.WriteJSON(event); err != nil {
+				return nil
+			}
+		case <-closed:
+			return nil
+		}
+	}
+}
+
+func (e *Events) NewEventsHandler(g *echo.Group) {
+	g.GET("/events/ws", e.GetEventsWs, mwares.JWTMiddleware)
+}
@@ -0,0 +1,109 @@
+package endpoints
+
+import (
+	"backend/internal/export"
+	"backend/internal/model"
+	"backend/internal/mwares"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Export struct {
+	exporter *export.Exporter
+	access   *conversationAccess
+}
+
+func NewExport(exporter *export.Exporter, chatRepo model.UserChatRepository, userGroupRepo model.UserGroupRepository,
+	userChannelRepo model.UserChannelRepository) *Export {
+	return &Export{
+		access:   newConversationAccess(chatRepo, userGroupRepo, userChannelRepo),
+		exporter: exporter,
+	}
+}
+
+func parseTimeParam(c echo.Context, name string) (*time.Time, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (e *Export) export(c echo.Context, chatType model.MessageType, param string) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	chatid, err := strconv.ParseUint(c.Param(param), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	from, err := parseTimeParam(c, "from")
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	to, err := parseTimeParam(c, "to")
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ok, err := e.access.canRead(c.Request().Context(), userid, chatid, chatType)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "can not access this conversation",
+		})
+	}
+
+	archive, err := e.exporter.Build(c.Request().Context(), chatType, chatid, from, to)
+	if errors.Is(err, export.ErrConversationNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "this conversation does not exist",
+		})
+	}
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	filename := fmt.Sprintf("%s-%d-%s.zip", chatType, chatid, archive.ExportedAt.Format("20060102-150405"))
+	c.Response().Header().Set(echo.HeaderContentType, "application/zip")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().WriteHeader(http.StatusOK)
+
+	if err = e.exporter.WriteZip(c.Request().Context(), c.Response(), archive); err != nil {
+		log.Warnln("export interrupted", err)
+	}
+
+	return nil
+}
+
+func (e *Export) ExportChat(c echo.Context) error {
+	return e.export(c, model.TypePV, "chatid")
+}
+
+func (e *Export) ExportGroup(c echo.Context) error {
+	return e.export(c, model.TypeGP, "groupid")
+}
+
+func (e *Export) ExportChannel(c echo.Context) error {
+	return e.export(c, model.TypeCH, "channelid")
+}
+
+func (e *Export) NewExportHandler(g *echo.Group) {
+	g.GET("/chats/:chatid/export", e.ExportChat, mwares.JWTMiddleware)
+	g.GET("/groups/:groupid/export", e.ExportGroup, mwares.JWTMiddleware)
+	g.GET("/channels/:channelid/export", e.ExportChannel, mwares.JWTMiddleware)
+}
@@ -0,0 +1,376 @@
+package endpoints
+
+import (
+	"backend/internal/folders"
+	"backend/internal/model"
+	"backend/internal/mwares"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxFolders    = 20
+	maxFolderName = 64
+)
+
+type Folder struct {
+	repo      model.FolderRepository
+	evaluator *folders.Evaluator
+}
+
+func NewFolder(repo model.FolderRepository, evaluator *folders.Evaluator) *Folder {
+	return &Folder{
+		evaluator: evaluator,
+		repo:      repo,
+	}
+}
+
+// parseFolderEntries reads a list such as "PV:3,GP:12" into folder entries.
+func parseFolderEntries(raw string, mode model.FolderMode) ([]model.FolderEntry, error) {
+	var entries []model.FolderEntry
+	for _, item := range strings.Split(raw, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+
+		key, err := parseConversationKey(item)
+		if err != nil {
+			return nil, err
+		}
+		if key.Type != model.TypePV && key.Type != model.TypeGP {
+			return nil, errors.New("folders can only hold PV and GP conversations")
+		}
+
+		entries = append(entries, model.FolderEntry{
+			Type:   key.Type,
+			ChatID: key.ChatID,
+			Mode:   mode,
+		})
+	}
+
+	return entries, nil
+}
+
+func formBool(c echo.Context, name string, current bool) (bool, error) {
+	v := c.FormValue(name)
+	if v == "" {
+		return current, nil
+	}
+
+	return strconv.ParseBool(v)
+}
+
+// applyFolderForm copies the submitted fields onto folder. Include and
+// exclude lists are returned only when at least one of them was sent.
+func applyFolderForm(c echo.Context, folder *model.Folder) ([]model.FolderEntry, bool, error) {
+	if name := c.FormValue("name"); name != "" {
+		if utf8.RuneCountInString(name) > maxFolderName {
+			return nil, false, errors.New("folder name is too long")
+		}
+		folder.Name = name
+	}
+
+	var err error
+	if folder.UnreadOnly, err = formBool(c, "unreadonly", folder.UnreadOnly); err != nil {
+		return nil, false, err
+	}
+	if folder.GroupsOnly, err = formBool(c, "groupsonly", folder.GroupsOnly); err != nil {
+		return nil, false, err
+	}
+	if folder.ContactsOnly, err = formBool(c, "contactsonly", folder.ContactsOnly); err != nil {
+		return nil, false, err
+	}
+	if folder.ExcludeMuted, err = formBool(c, "excludemuted", folder.ExcludeMuted); err != nil {
+		return nil, false, err
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return nil, false, err
+	}
+	_, hasInclude := form["include"]
+	_, hasExclude := form["exclude"]
+	if !hasInclude && !hasExclude {
+		return nil, false, nil
+	}
+
+	include, err := parseFolderEntries(c.FormValue("include"), model.FolderInclude)
+	if err != nil {
+		return nil, false, err
+	}
+	exclude, err := parseFolderEntries(c.FormValue("exclude"), model.FolderExclude)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return append(include, exclude...), true, nil
+}
+
+// folderFilter returns the conversations in the folder named by the
+// "folder" query parameter, or nil when no folder was requested.
+func folderFilter(c echo.Context, evaluator *folders.Evaluator, userID uint64) (map[folders.Key]bool, error) {
+	v := c.QueryParam("folder")
+	if v == "" {
+		return nil, nil
+	}
+
+	folderID, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return nil, echo.ErrBadRequest
+	}
+
+	matched, err := evaluator.Filter(c.Request().Context(), userID, folderID)
+	if errors.Is(err, folders.ErrFolderNotFound) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "folder not found")
+	}
+	if err != nil {
+		return nil, echo.ErrInternalServerError
+	}
+
+	return matched, nil
+}
+
+func (f *Folder) GetFolders(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	userFolders, err := f.repo.Get(c.Request().Context(), model.FolderInterface{
+		UserID: &userid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	folderIDs := make([]uint64, len(userFolders))
+	for i, folder := range userFolders {
+		folderIDs[i] = folder.FolderID
+	}
+
+	allEntries, err := f.repo.GetEntries(c.Request().Context(), folderIDs)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	entries := make(map[uint64][]model.FolderEntry)
+	for _, entry := range allEntries {
+		entries[entry.FolderID] = append(entries[entry.FolderID], entry)
+	}
+
+	totals, err := f.evaluator.UnreadTotals(c.Request().Context(), userid, userFolders, entries)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	type response struct {
+		model.Folder
+		Entries []model.FolderEntry `json:"entries"`
+		Unread  int64               `json:"unread"`
+	}
+
+	res := make([]response, len(userFolders))
+	for i, folder := range userFolders {
+		res[i] = response{
+			Folder:  folder,
+			Entries: entries[folder.FolderID],
+			Unread:  totals[folder.FolderID],
+		}
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func (f *Folder) NewFolder(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	if c.FormValue("name") == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "folder name can not be empty",
+		})
+	}
+
+	userFolders, err := f.repo.Get(c.Request().Context(), model.FolderInterface{
+		UserID: &userid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if len(userFolders) >= maxFolders {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "folder limit reached",
+		})
+	}
+
+	folder := model.Folder{
+		UserID:   userid,
+		Position: len(userFolders),
+	}
+
+	entries, _, err := applyFolderForm(c, &folder)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": err.Error(),
+		})
+	}
+
+	folderID, err := f.repo.Create(c.Request().Context(), folder, entries)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"msg":      "folder created",
+		"folderID": folderID,
+	})
+}
+
+func (f *Folder) UpdateFolder(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	folderid, err := strconv.ParseUint(c.Param("folderid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	userFolders, err := f.repo.Get(c.Request().Context(), model.FolderInterface{
+		ID:     &folderid,
+		UserID: &userid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if len(userFolders) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "folder not found",
+		})
+	}
+
+	folder := userFolders[0]
+	entries, replace, err := applyFolderForm(c, &folder)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": err.Error(),
+		})
+	}
+
+	if replace {
+		err = f.repo.Replace(c.Request().Context(), folder, entries)
+	} else {
+		err = f.repo.Update(c.Request().Context(), folder)
+	}
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "folder updated",
+	})
+}
+
+func (f *Folder) ReorderFolders(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	userFolders, err := f.repo.Get(c.Request().Context(), model.FolderInterface{
+		UserID: &userid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	byID := make(map[uint64]model.Folder, len(userFolders))
+	for _, folder := range userFolders {
+		byID[folder.FolderID] = folder
+	}
+
+	var order []uint64
+	seen := make(map[uint64]bool, len(userFolders))
+	for _, item := range strings.Split(c.FormValue("order"), ",") {
+		folderID, err := strconv.ParseUint(strings.TrimSpace(item), 10, 64)
+		if err != nil {
+			return echo.ErrBadRequest
+		}
+		if _, ok := byID[folderID]; !ok {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "order must list your folders only",
+			})
+		}
+		if seen[folderID] {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "order must list every folder exactly once",
+			})
+		}
+		seen[folderID] = true
+		order = append(order, folderID)
+	}
+
+	if len(order) != len(userFolders) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "order must list every folder exactly once",
+		})
+	}
+
+	for position, folderID := range order {
+		folder := byID[folderID]
+		folder.Position = position
+		if err = f.repo.Update(c.Request().Context(), folder); err != nil {
+			return echo.ErrInternalServerError
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "folders reordered",
+	})
+}
+
+func (f *Folder) DeleteFolder(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	folderid, err := strconv.ParseUint(c.Param("folderid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	userFolders, err := f.repo.Get(c.Request().Context(), model.FolderInterface{
+		ID:     &folderid,
+		UserID: &userid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if len(userFolders) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "folder not found",
+		})
+	}
+
+	if err = f.repo.Delete(c.Request().Context(), model.FolderInterface{
+		ID:     &folderid,
+		UserID: &userid,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "folder deleted",
+	})
+}
+
+func (f *Folder) NewFolderHandler(g *echo.Group) {
+	folderGroup := g.Group("/folders")
+
+	folderGroup.GET("", f.GetFolders, mwares.JWTMiddleware)
+	folderGroup.POST("", f.NewFolder, mwares.JWTMiddleware)
+	folderGroup.PUT("/order", f.ReorderFolders, mwares.JWTMiddleware)
+	folderGroup.PATCH("/:folderid", f.UpdateFolder, mwares.JWTMiddleware)
+	folderGroup.DELETE("/:folderid", f.DeleteFolder, mwares.JWTMiddleware)
+}
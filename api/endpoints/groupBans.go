@@ -0,0 +1,207 @@
+package endpoints
+
+import (
+	"backend/internal/membership"
+	"backend/internal/model"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const maxBanReason = 500
+
+// checkBan refuses userID entry into groupID while they are banned from it.
+func (g *Group) checkBan(c echo.Context, groupID, userID uint64) error {
+	ban, err := g.membership.CheckBan(c.Request().Context(), groupID, userID)
+	if errors.Is(err, membership.ErrBanned) {
+		return echo.NewHTTPError(http.StatusForbidden, echo.Map{
+			"msg":       "user is banned from this group",
+			"expiresAt": ban.ExpiresAt,
+		})
+	}
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return nil
+}
+
+// formUntil reads an end time from "until" (RFC 3339) or "duration" (such
+// as "36h"). It returns nil when neither was sent.
+func formUntil(c echo.Context) (*time.Time, error) {
+	if v := c.FormValue("until"); v != "" {
+		until, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, errors.New("until must be an RFC 3339 time")
+		}
+		if !until.After(time.Now()) {
+			return nil, errors.New("until must be in the future")
+		}
+		return &until, nil
+	}
+
+	if v := c.FormValue("duration"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, errors.New("duration must be a positive duration such as 36h")
+		}
+		until := time.Now().Add(d)
+		return &until, nil
+	}
+
+	return nil, nil
+}
+
+// BanMember bans a user from the group, removing them if they are in it.
+// Banning again replaces the reason and expiry, which takes a role at least
+// that of whoever placed the ban.
+func (g *Group) BanMember(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	targetid, err := strconv.ParseUint(c.Param("userid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	actor, err := g.authorize(c, groupID, userid, model.GroupRemoveMembers)
+	if err != nil {
+		return err
+	}
+
+	if targetid == userid {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "you can not ban yourself",
+		})
+	}
+
+	reason := c.FormValue("reason")
+	if utf8.RuneCountInString(reason) > maxBanReason {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "ban reason is too long",
+		})
+	}
+
+	until, err := formUntil(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": err.Error(),
+		})
+	}
+
+	target, err := g.member(c, groupID, targetid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if target != nil && !actor.Outranks(*target) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"msg": "you can not ban a member whose role is not below yours",
+		})
+	}
+
+	ban := model.GroupBan{
+		GroupID:   groupID,
+		UserID:    targetid,
+		BannedBy:  userid,
+		Reason:    reason,
+		ExpiresAt: until,
+	}
+
+	err = g.membership.Ban(c.Request().Context(), ban)
+	if errors.Is(err, membership.ErrOwnerBan) || errors.Is(err, membership.ErrBannerRank) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"msg": err.Error(),
+		})
+	}
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	// A banned user's pending requests can no longer be approved.
+	status := model.JoinPending
+	pending, err := g.joinRequestRepo.Get(c.Request().Context(), model.JoinRequestInterface{
+		GroupID: &groupID,
+		UserID:  &targetid,
+		Status:  &status,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	for _, request := range pending {
+		if _, err = g.joinRequestRepo.Decide(c.Request().Context(), request.RequestID, model.JoinDeclined, userid); err != nil {
+			return echo.ErrInternalServerError
+		}
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg": "user banned",
+		"ban": ban,
+	})
+}
+
+func (g *Group) GetBans(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupRemoveMembers); err != nil {
+		return err
+	}
+
+	bans, err := g.membership.Bans(c.Request().Context(), groupID)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, bans)
+}
+
+func (g *Group) UnbanMember(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	targetid, err := strconv.ParseUint(c.Param("userid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupRemoveMembers); err != nil {
+		return err
+	}
+
+	lifted, err := g.membership.Unban(c.Request().Context(), groupID, targetid, userid)
+	if errors.Is(err, membership.ErrBannerRank) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"msg": err.Error(),
+		})
+	}
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if !lifted {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "user is not banned from this group",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "ban lifted",
+	})
+}
@@ -0,0 +1,351 @@
+package endpoints
+
+import (
+	"backend/internal/model"
+	"crypto/rand"
+	"encoding/base64"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type inviteStats struct {
+	model.GroupInviteDTO
+	Valid     bool  `json:"valid"`
+	Remaining *int  `json:"remaining"`
+	Members   int64 `json:"members"`
+}
+
+func newInviteToken() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// withStats adds validity, remaining uses and how many members who joined
+// through each invite are still in the group.
+func (g *Group) withStats(c echo.Context, groupID uint64, invites []model.GroupInviteDTO) ([]inviteStats, error) {
+	members, err := g.userGroupRepo.Get(c.Request().Context(), model.UserGroupInterface{
+		GroupID: &groupID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	joined := make(map[uint64]int64)
+	for _, member := range members {
+		if member.InviteID != 0 {
+			joined[member.InviteID]++
+		}
+	}
+
+	now := time.Now()
+	stats := make([]inviteStats, len(invites))
+	for i, invite := range invites {
+		stats[i] = inviteStats{
+			GroupInviteDTO: invite,
+			Valid:          invite.Valid(now),
+			Members:        joined[invite.InviteID],
+		}
+		if invite.MaxUses > 0 {
+			remaining := invite.MaxUses - invite.Uses
+			if remaining < 0 {
+				remaining = 0
+			}
+			stats[i].Remaining = &remaining
+		}
+	}
+
+	return stats, nil
+}
+
+// findInvite loads a group's invite from the :inviteid path parameter.
+func (g *Group) findInvite(c echo.Context, groupID uint64) (*model.GroupInviteDTO, error) {
+	inviteid, err := strconv.ParseUint(c.Param("inviteid"), 10, 64)
+	if err != nil {
+		return nil, echo.ErrBadRequest
+	}
+
+	invites, err := g.inviteRepo.Get(c.Request().Context(), model.GroupInviteInterface{
+		ID:      &inviteid,
+		GroupID: &groupID,
+	})
+	if err != nil {
+		return nil, echo.ErrInternalServerError
+	}
+	if len(invites) == 0 {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "invite not found")
+	}
+
+	return &invites[0], nil
+}
+
+// validInvite loads the invite for the :token path parameter, failing the
+// same way for unknown, revoked, expired and used-up links.
+func (g *Group) validInvite(c echo.Context) (*model.GroupInviteDTO, error) {
+	token := c.Param("token")
+
+	invites, err := g.inviteRepo.Get(c.Request().Context(), model.GroupInviteInterface{
+		Token: &token,
+	})
+	if err != nil {
+		return nil, echo.ErrInternalServerError
+	}
+	if len(invites) == 0 || !invites[0].Valid(time.Now()) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "invite link is invalid or has expired")
+	}
+
+	return &invites[0], nil
+}
+
+func (g *Group) NewInvite(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupAddMembers); err != nil {
+		return err
+	}
+
+	invite := model.GroupInvite{
+		GroupID:   groupID,
+		CreatedBy: userid,
+	}
+
+	if v := c.FormValue("expiresat"); v != "" {
+		expiresAt, err := time.Parse(time.RFC3339, v)
+		if err != nil || !expiresAt.After(time.Now()) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "expiresat must be an RFC3339 time in the future",
+			})
+		}
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if v := c.FormValue("maxuses"); v != "" {
+		maxUses, err := strconv.Atoi(v)
+		if err != nil || maxUses < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "maxuses must be zero or a positive number",
+			})
+		}
+		invite.MaxUses = maxUses
+	}
+
+	if invite.RequiresApproval, err = formBool(c, "requiresapproval", false); err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if invite.Token, err = newInviteToken(); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if invite.InviteID, err = g.inviteRepo.Create(c.Request().Context(), invite); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"msg":    "invite created",
+		"invite": invite,
+	})
+}
+
+func (g *Group) GetInvites(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupAddMembers); err != nil {
+		return err
+	}
+
+	invites, err := g.inviteRepo.Get(c.Request().Context(), model.GroupInviteInterface{
+		GroupID: &groupID,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	stats, err := g.withStats(c, groupID, invites)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+func (g *Group) GetInvite(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupAddMembers); err != nil {
+		return err
+	}
+
+	invite, err := g.findInvite(c, groupID)
+	if err != nil {
+		return err
+	}
+
+	stats, err := g.withStats(c, groupID, []model.GroupInviteDTO{*invite})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, stats[0])
+}
+
+func (g *Group) RevokeInvite(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupAddMembers); err != nil {
+		return err
+	}
+
+	invite, err := g.findInvite(c, groupID)
+	if err != nil {
+		return err
+	}
+
+	if err = g.inviteRepo.Revoke(c.Request().Context(), invite.InviteID); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "invite revoked",
+	})
+}
+
+// PreviewInvite shows what group a link leads to before joining it.
+func (g *Group) PreviewInvite(c echo.Context) error {
+	invite, err := g.validInvite(c)
+	if err != nil {
+		return err
+	}
+
+	groups, err := g.repo.Get(c.Request().Context(), model.GroupInterface{
+		ID: &invite.GroupID,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if len(groups) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "group not found",
+		})
+	}
+
+	count, err := g.userGroupRepo.Count(c.Request().Context(), invite.GroupID)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"groupID":          groups[0].GroupID,
+		"name":             groups[0].Name,
+		"description":      groups[0].Description,
+		"members":          count,
+		"requiresApproval": invite.RequiresApproval || groups[0].RequiresApproval,
+	})
+}
+
+func (g *Group) JoinByInvite(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	invite, err := g.validInvite(c)
+	if err != nil {
+		return err
+	}
+
+	existing, err := g.member(c, invite.GroupID, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if existing != nil {
+		return c.JSON(http.StatusOK, echo.Map{
+			"msg":     "you are already a member of this group",
+			"groupID": invite.GroupID,
+		})
+	}
+
+	if err = g.checkBan(c, invite.GroupID, userid); err != nil {
+		return err
+	}
+
+	groups, err := g.repo.Get(c.Request().Context(), model.GroupInterface{
+		ID: &invite.GroupID,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if len(groups) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "group not found",
+		})
+	}
+
+	if invite.RequiresApproval || groups[0].RequiresApproval {
+		request, _, err := g.fileJoinRequest(c, invite.GroupID, userid, c.FormValue("message"), invite.InviteID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusAccepted, echo.Map{
+			"msg":     "join request sent for approval",
+			"groupID": invite.GroupID,
+			"request": request,
+		})
+	}
+
+	ok, err := g.inviteRepo.Use(c.Request().Context(), invite.InviteID)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "invite link is invalid or has expired",
+		})
+	}
+
+	if err = g.userGroupRepo.Create(c.Request().Context(), model.UserGroup{
+		GroupID:  invite.GroupID,
+		UserID:   userid,
+		Role:     model.GroupMember,
+		InviteID: invite.InviteID,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if err = g.membership.Joined(c.Request().Context(), invite.GroupID, userid, userid); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"msg":     "joined group",
+		"groupID": invite.GroupID,
+	})
+}
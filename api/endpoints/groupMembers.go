@@ -0,0 +1,98 @@
+package endpoints
+
+import (
+	"backend/internal/blocking"
+	"backend/internal/model"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultMemberLimit = 50
+	maxMemberLimit     = 200
+)
+
+// GetGroupMembers lists a group's members with their profiles, role and
+// join date, in join order. "q" searches names and usernames, "role"
+// filters by role, and "cursor" continues from a previous page.
+func (g *Group) GetGroupMembers(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupRead); err != nil {
+		return err
+	}
+
+	q := model.GroupMemberQuery{
+		GroupID: groupID,
+		Search:  strings.TrimSpace(c.QueryParam("q")),
+		Limit:   defaultMemberLimit,
+	}
+
+	if v := c.QueryParam("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return echo.ErrBadRequest
+		}
+		if limit > maxMemberLimit {
+			limit = maxMemberLimit
+		}
+		q.Limit = limit
+	}
+
+	if v := c.QueryParam("cursor"); v != "" {
+		if q.After, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return echo.ErrBadRequest
+		}
+	}
+
+	if v := c.QueryParam("role"); v != "" {
+		role := model.GroupRole(v)
+		switch role {
+		case model.GroupOwner, model.GroupAdmin, model.GroupMember, model.GroupRestricted:
+		default:
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "role must be owner, admin, member or restricted",
+			})
+		}
+		q.Role = &role
+	}
+
+	members, err := g.userGroupRepo.Members(c.Request().Context(), q)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	blockers, err := g.blocks.BlockedBy(c.Request().Context(), userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	for i := range members {
+		if blockers[members[i].UserID] {
+			blocking.HideProfile(&members[i].ProfilePicture, &members[i].IsActive, &members[i].LastSeen)
+		}
+	}
+
+	count, err := g.userGroupRepo.Count(c.Request().Context(), groupID)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	var nextCursor string
+	if len(members) == q.Limit {
+		nextCursor = strconv.FormatUint(members[len(members)-1].UserGroupID, 10)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"count":      count,
+		"members":    members,
+		"nextCursor": nextCursor,
+	})
+}
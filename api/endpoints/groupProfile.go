@@ -0,0 +1,330 @@
+package endpoints
+
+import (
+	"backend/internal/configs"
+	"backend/internal/model"
+	"backend/utils/datasource"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	EventGroupUpdated = "group.updated"
+
+	maxGroupName        = 255
+	maxGroupDescription = 255
+	maxGroupAvatarSize  = 5 << 20
+)
+
+// avatarTypes are the content types accepted for a group avatar, as sniffed
+// from the file itself rather than taken from the client.
+var avatarTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// avatarType sniffs the content type of the uploaded file.
+func avatarType(avatar *multipart.FileHeader) (string, error) {
+	file, err := avatar.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return http.DetectContentType(head[:n]), nil
+}
+
+// uploadGroupAvatar stores the "avatar" form file in S3 and returns its key,
+// or "" when no file was sent. Files that are too large or not an image are
+// refused with an error ready to be returned by the handler. Every upload
+// gets a fresh key so a new avatar never overwrites one still being served.
+func uploadGroupAvatar(c echo.Context, groupID uint64) (string, error) {
+	avatar, err := c.FormFile("avatar")
+	if err != nil {
+		return "", nil
+	}
+
+	if avatar.Size > maxGroupAvatarSize {
+		return "", echo.NewHTTPError(http.StatusRequestEntityTooLarge, echo.Map{
+			"msg": fmt.Sprintf("avatar can not be larger than %d bytes", maxGroupAvatarSize),
+		})
+	}
+
+	contentType, err := avatarType(avatar)
+	if err != nil {
+		return "", echo.ErrBadRequest
+	}
+	if !avatarTypes[contentType] {
+		return "", echo.NewHTTPError(http.StatusUnsupportedMediaType, echo.Map{
+			"msg": "avatar must be a jpeg, png, gif or webp image",
+		})
+	}
+	avatar.Header.Set("Content-Type", contentType)
+
+	suffix := make([]byte, 8)
+	if _, err = rand.Read(suffix); err != nil {
+		return "", echo.ErrInternalServerError
+	}
+
+	conf, err := configs.LoadConfig()
+	if err != nil {
+		log.Errorln("cant open config file")
+		return "", echo.ErrInternalServerError
+	}
+
+	sess, err := datasource.ConnectS3(conf.S3.AccessKey, conf.S3.SecretKey, conf.S3.Region, conf.S3.Endpoint)
+	if err != nil {
+		log.Errorln("can not connect to S3")
+		return "", echo.ErrInternalServerError
+	}
+
+	key, err := datasource.UploadS3(sess, avatar, conf.S3.Bucket,
+		fmt.Sprintf("group-%d-%s-", groupID, hex.EncodeToString(suffix)))
+	if err != nil {
+		log.Errorln("can not upload to s3")
+		return "", echo.ErrInternalServerError
+	}
+
+	return key, nil
+}
+
+// UpdateGroup edits the group's name, description and avatar. Each field
+// that actually changes is recorded in the group's history, announced in
+// its timeline, and the updated group is sent to every member.
+func (g *Group) UpdateGroup(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupEditInfo); err != nil {
+		return err
+	}
+
+	groups, err := g.repo.Get(c.Request().Context(), model.GroupInterface{
+		ID: &groupID,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if len(groups) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "group not found",
+		})
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	old := groups[0]
+	group := old
+	if _, ok := form["name"]; ok {
+		group.Name = strings.TrimSpace(c.FormValue("name"))
+		if group.Name == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "group name can not be empty",
+			})
+		}
+		if utf8.RuneCountInString(group.Name) > maxGroupName {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "group name is too long",
+			})
+		}
+	}
+	if _, ok := form["description"]; ok {
+		group.Description = c.FormValue("description")
+		if utf8.RuneCountInString(group.Description) > maxGroupDescription {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "group description is too long",
+			})
+		}
+	}
+
+	removeAvatar, err := formBool(c, "removeavatar", false)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	if removeAvatar {
+		group.Avatar = ""
+	}
+
+	avatar, err := uploadGroupAvatar(c, groupID)
+	if err != nil {
+		return err
+	}
+	if avatar != "" {
+		group.Avatar = avatar
+	}
+
+	var changes []model.GroupProfileChange
+	var announcements []model.SystemPayload
+	record := func(field model.GroupProfileField, event, before, after string) {
+		if before == after {
+			return
+		}
+		changes = append(changes, model.GroupProfileChange{
+			GroupID:   groupID,
+			ChangedBy: userid,
+			Field:     field,
+			OldValue:  before,
+			NewValue:  after,
+		})
+		announcements = append(announcements, model.SystemPayload{
+			Event:   event,
+			ActorID: userid,
+			Old:     before,
+			New:     after,
+		})
+	}
+	record(model.GroupFieldName, model.SystemTitleChanged, old.Name, group.Name)
+	record(model.GroupFieldDescription, model.SystemDescriptionChanged, old.Description, group.Description)
+	record(model.GroupFieldAvatar, model.SystemAvatarChanged, old.Avatar, group.Avatar)
+
+	if len(changes) == 0 {
+		return c.JSON(http.StatusOK, echo.Map{
+			"msg":   "nothing to update",
+			"group": group,
+		})
+	}
+
+	if err = g.repo.UpdateProfile(c.Request().Context(), group); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if err = g.profileChangeRepo.Create(c.Request().Context(), changes); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	for _, announcement := range announcements {
+		if err = g.membership.Announce(c.Request().Context(), groupID, announcement); err != nil {
+			return echo.ErrInternalServerError
+		}
+	}
+
+	members, err := g.userGroupRepo.Get(c.Request().Context(), model.UserGroupInterface{
+		GroupID: &groupID,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	recipients := make([]uint64, len(members))
+	for i, member := range members {
+		recipients[i] = member.UserID
+	}
+	g.hub.PublishMany(recipients, EventGroupUpdated, group)
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":   "group updated",
+		"group": group,
+	})
+}
+
+func (g *Group) GetGroupHistory(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupRead); err != nil {
+		return err
+	}
+
+	var field *model.GroupProfileField
+	if v := c.QueryParam("field"); v != "" {
+		f := model.GroupProfileField(v)
+		if f != model.GroupFieldName && f != model.GroupFieldDescription && f != model.GroupFieldAvatar {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "field must be name, description or avatar",
+			})
+		}
+		field = &f
+	}
+
+	changes, err := g.profileChangeRepo.Get(c.Request().Context(), model.GroupProfileChangeInterface{
+		GroupID: &groupID,
+		Field:   field,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, changes)
+}
+
+func (g *Group) GetGroupAvatar(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupRead); err != nil {
+		return err
+	}
+
+	groups, err := g.repo.Get(c.Request().Context(), model.GroupInterface{
+		ID: &groupID,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if len(groups) == 0 || groups[0].Avatar == "" {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "group has no avatar",
+		})
+	}
+
+	conf, err := configs.LoadConfig()
+	if err != nil {
+		log.Errorln("cant open config file")
+		return echo.ErrInternalServerError
+	}
+
+	sess, err := datasource.ConnectS3(conf.S3.AccessKey, conf.S3.SecretKey, conf.S3.Region, conf.S3.Endpoint)
+	if err != nil {
+		log.Errorln("can not connect to S3")
+		return echo.ErrInternalServerError
+	}
+
+	body, contentType, err := datasource.OpenS3(sess, conf.S3.Bucket, groups[0].Avatar)
+	if err != nil {
+		log.Errorln("can not download from s3")
+		return echo.ErrInternalServerError
+	}
+	defer body.Close()
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	return c.Stream(http.StatusOK, contentType, body)
+}
@@ -0,0 +1,150 @@
+package endpoints
+
+import (
+	"backend/internal/model"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxGroupRules   = 4000
+	maxGroupWelcome = 1000
+)
+
+// GetRules returns the group's rules and welcome template, and when the
+// caller accepted the rules.
+func (g *Group) GetRules(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	member, err := g.authorize(c, groupID, userid, model.GroupRead)
+	if err != nil {
+		return err
+	}
+
+	groups, err := g.repo.Get(c.Request().Context(), model.GroupInterface{
+		ID: &groupID,
+	})
+	if err != nil || len(groups) == 0 {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"rules":           groups[0].Rules,
+		"welcomeMessage":  groups[0].WelcomeMessage,
+		"rulesRequired":   groups[0].RulesRequired,
+		"rulesAcceptedAt": member.RulesAcceptedAt,
+		"mustAccept":      member.MustAcceptRules(groups[0]),
+	})
+}
+
+// SetRules changes the rules, the welcome template and whether members must
+// accept the rules before posting. Fields that are not sent keep their
+// value; sending one empty clears it. The template may use {name} and
+// {group}. Changing required rules asks every member to accept them again.
+func (g *Group) SetRules(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupEditInfo); err != nil {
+		return err
+	}
+
+	groups, err := g.repo.Get(c.Request().Context(), model.GroupInterface{
+		ID: &groupID,
+	})
+	if err != nil || len(groups) == 0 {
+		return echo.ErrInternalServerError
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	group := groups[0]
+	if _, ok := form["rules"]; ok {
+		group.Rules = c.FormValue("rules")
+		if utf8.RuneCountInString(group.Rules) > maxGroupRules {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "group rules are too long",
+			})
+		}
+	}
+	if _, ok := form["welcome"]; ok {
+		group.WelcomeMessage = c.FormValue("welcome")
+		if utf8.RuneCountInString(group.WelcomeMessage) > maxGroupWelcome {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "welcome message is too long",
+			})
+		}
+	}
+	if group.RulesRequired, err = formBool(c, "required", group.RulesRequired); err != nil {
+		return echo.ErrBadRequest
+	}
+	if group.RulesRequired && group.Rules == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "write the rules before requiring members to accept them",
+		})
+	}
+
+	// Members accepted the old text; once it changes they have to accept
+	// the new one before posting again.
+	resetAcceptance := group.RulesRequired && group.Rules != groups[0].Rules
+	if err = g.repo.SetRules(c.Request().Context(), group, resetAcceptance); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":            "group rules updated",
+		"rules":          group.Rules,
+		"welcomeMessage": group.WelcomeMessage,
+		"rulesRequired":  group.RulesRequired,
+	})
+}
+
+// AcceptRules records that the caller accepted the group rules, which lets
+// them post once the group requires it.
+func (g *Group) AcceptRules(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	member, err := g.authorize(c, groupID, userid, model.GroupRead)
+	if err != nil {
+		return err
+	}
+	if member.RulesAcceptedAt != nil {
+		return c.JSON(http.StatusOK, echo.Map{
+			"msg":             "rules already accepted",
+			"rulesAcceptedAt": member.RulesAcceptedAt,
+		})
+	}
+
+	now := time.Now()
+	if err = g.userGroupRepo.AcceptRules(c.Request().Context(), groupID, userid, now); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":             "rules accepted",
+		"rulesAcceptedAt": now,
+	})
+}
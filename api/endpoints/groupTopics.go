@@ -0,0 +1,383 @@
+package endpoints
+
+import (
+	"backend/internal/model"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	EventTopicUpdated = "group.topic"
+
+	maxTopicTitle = 128
+)
+
+// topic loads topicID of groupID, failing with 404 when the group has no
+// such topic.
+func (g *Group) topic(c echo.Context, groupID, topicID uint64) (*model.GroupTopicDTO, error) {
+	topics, err := g.topicRepo.Get(c.Request().Context(), model.GroupTopicInterface{
+		ID:      &topicID,
+		GroupID: &groupID,
+	})
+	if err != nil {
+		return nil, echo.ErrInternalServerError
+	}
+	if len(topics) == 0 {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "topic not found")
+	}
+
+	return &topics[0], nil
+}
+
+// topicEvent is the payload of EventTopicUpdated. Deleted is set once the
+// topic and its messages are gone.
+type topicEvent struct {
+	model.GroupTopicDTO
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// publishTopic sends the topic's current state to every member.
+func (g *Group) publishTopic(c echo.Context, topic model.GroupTopicDTO, deleted bool) error {
+	members, err := g.userGroupRepo.Get(c.Request().Context(), model.UserGroupInterface{
+		GroupID: &topic.GroupID,
+	})
+	if err != nil {
+		return err
+	}
+
+	recipients := make([]uint64, len(members))
+	for i, member := range members {
+		recipients[i] = member.UserID
+	}
+	g.hub.PublishMany(recipients, EventTopicUpdated, topicEvent{
+		GroupTopicDTO: topic,
+		Deleted:       deleted,
+	})
+
+	return nil
+}
+
+func topicTitle(c echo.Context) (string, error) {
+	title := strings.TrimSpace(c.FormValue("title"))
+	if title == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, map[string]string{
+			"msg": "topic title can not be empty",
+		})
+	}
+	if utf8.RuneCountInString(title) > maxTopicTitle {
+		return "", echo.NewHTTPError(http.StatusBadRequest, map[string]string{
+			"msg": "topic title is too long",
+		})
+	}
+
+	return title, nil
+}
+
+// SetForumMode turns forum mode on or off. Topics can only be created while
+// it is on; turning it off keeps existing topics and their messages.
+func (g *Group) SetForumMode(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupEditInfo); err != nil {
+		return err
+	}
+
+	enabled, err := strconv.ParseBool(c.FormValue("enabled"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if err = g.repo.SetForumMode(c.Request().Context(), groupID, enabled); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":       "group updated",
+		"forumMode": enabled,
+	})
+}
+
+func (g *Group) NewTopic(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupManageTopics); err != nil {
+		return err
+	}
+
+	groups, err := g.repo.Get(c.Request().Context(), model.GroupInterface{
+		ID: &groupID,
+	})
+	if err != nil || len(groups) == 0 {
+		return echo.ErrInternalServerError
+	}
+	if !groups[0].ForumMode {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "forum mode is off in this group",
+		})
+	}
+
+	title, err := topicTitle(c)
+	if err != nil {
+		return err
+	}
+
+	topicID, err := g.topicRepo.Create(c.Request().Context(), model.GroupTopic{
+		GroupID:   groupID,
+		Title:     title,
+		CreatedBy: userid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	topic, err := g.topic(c, groupID, topicID)
+	if err != nil {
+		return err
+	}
+
+	if err = g.membership.Announce(c.Request().Context(), groupID, model.SystemPayload{
+		Event:   model.SystemTopicCreated,
+		ActorID: userid,
+		New:     title,
+		TopicID: topicID,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if err = g.publishTopic(c, *topic, false); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"msg":   "topic created",
+		"topic": topic,
+	})
+}
+
+func (g *Group) GetTopics(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupRead); err != nil {
+		return err
+	}
+
+	topics, err := g.topicRepo.List(c.Request().Context(), groupID, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, topics)
+}
+
+// UpdateTopic renames a topic and opens or closes it. Only members who may
+// manage topics can post in a closed one.
+func (g *Group) UpdateTopic(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	topicID, err := strconv.ParseUint(c.Param("topicid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupManageTopics); err != nil {
+		return err
+	}
+
+	topic, err := g.topic(c, groupID, topicID)
+	if err != nil {
+		return err
+	}
+
+	update := topic.GroupTopic
+	if c.FormValue("title") != "" {
+		if update.Title, err = topicTitle(c); err != nil {
+			return err
+		}
+	}
+	if update.Closed, err = formBool(c, "closed", update.Closed); err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if err = g.topicRepo.Update(c.Request().Context(), update); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	var announcements []model.SystemPayload
+	if update.Title != topic.Title {
+		announcements = append(announcements, model.SystemPayload{
+			Event:   model.SystemTitleChanged,
+			ActorID: userid,
+			Old:     topic.Title,
+			New:     update.Title,
+			TopicID: topicID,
+		})
+	}
+	if update.Closed != topic.Closed {
+		event := model.SystemTopicReopened
+		if update.Closed {
+			event = model.SystemTopicClosed
+		}
+		announcements = append(announcements, model.SystemPayload{
+			Event:   event,
+			ActorID: userid,
+			TopicID: topicID,
+		})
+	}
+	for _, announcement := range announcements {
+		if err = g.membership.Announce(c.Request().Context(), groupID, announcement); err != nil {
+			return echo.ErrInternalServerError
+		}
+	}
+
+	topic.GroupTopic = update
+	if err = g.publishTopic(c, *topic, false); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":   "topic updated",
+		"topic": topic,
+	})
+}
+
+// DeleteTopic removes a topic together with its messages.
+func (g *Group) DeleteTopic(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	topicID, err := strconv.ParseUint(c.Param("topicid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupManageTopics); err != nil {
+		return err
+	}
+
+	topic, err := g.topic(c, groupID, topicID)
+	if err != nil {
+		return err
+	}
+
+	if err = g.topicRepo.Delete(c.Request().Context(), model.GroupTopicInterface{
+		ID: &topicID,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if err = g.publishTopic(c, *topic, true); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "topic deleted",
+	})
+}
+
+// PinTopicMessage pins a message of the topic; DELETE unpins it.
+func (g *Group) PinTopicMessage(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	topicID, err := strconv.ParseUint(c.Param("topicid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupPinMessages); err != nil {
+		return err
+	}
+
+	topic, err := g.topic(c, groupID, topicID)
+	if err != nil {
+		return err
+	}
+
+	update := topic.GroupTopic
+	update.PinnedMessageID = 0
+	if c.Request().Method == http.MethodPut {
+		messageID, err := strconv.ParseUint(c.FormValue("messageid"), 10, 64)
+		if err != nil {
+			return echo.ErrBadRequest
+		}
+
+		chatType := model.TypeGP
+		messages, err := g.messageRepo.Get(c.Request().Context(), model.MessageInterface{
+			ID:      &messageID,
+			ChatID:  &groupID,
+			TopicID: &topicID,
+			Type:    &chatType,
+		})
+		if err != nil {
+			return echo.ErrInternalServerError
+		}
+		if len(messages) == 0 {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"msg": "message not found in this topic",
+			})
+		}
+		update.PinnedMessageID = messageID
+	}
+
+	if err = g.topicRepo.Update(c.Request().Context(), update); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if update.PinnedMessageID != 0 {
+		if err = g.membership.Announce(c.Request().Context(), groupID, model.SystemPayload{
+			Event:     model.SystemMessagePinned,
+			ActorID:   userid,
+			MessageID: update.PinnedMessageID,
+			TopicID:   topicID,
+		}); err != nil {
+			return echo.ErrInternalServerError
+		}
+	}
+
+	topic.GroupTopic = update
+	if err = g.publishTopic(c, *topic, false); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":   "topic updated",
+		"topic": topic,
+	})
+}
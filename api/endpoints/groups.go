@@ -1,32 +1,97 @@
 package endpoints
 
 import (
+	"backend/internal/blocking"
+	"backend/internal/events"
+	"backend/internal/flood"
+	"backend/internal/folders"
+	"backend/internal/membership"
 	"backend/internal/model"
 	"backend/internal/mwares"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
 	"net/http"
 	"sort"
 	"strconv"
+	"time"
+	"unicode/utf8"
 )
 
 type Group struct {
-	repo          model.GroupRepository
-	messageRepo   model.MessageRepository
-	userGroupRepo model.UserGroupRepository
-	groupChatRepo model.GroupChatRepository
+	repo              model.GroupRepository
+	messageRepo       model.MessageRepository
+	userGroupRepo     model.UserGroupRepository
+	inviteRepo        model.GroupInviteRepository
+	joinRequestRepo   model.JoinRequestRepository
+	profileChangeRepo model.GroupProfileChangeRepository
+	topicRepo         model.GroupTopicRepository
+	settingRepo       model.ConversationSettingRepository
+	folders           *folders.Evaluator
+	membership        *membership.Service
+	limiter           *flood.Limiter
+	blocks            *blocking.Service
+	hub               *events.Hub
 }
 
 func NewGroup(repo model.GroupRepository, messageRepo model.MessageRepository, userGroupRepo model.UserGroupRepository,
-	groupChatRepo model.GroupChatRepository) *Group {
+	inviteRepo model.GroupInviteRepository, joinRequestRepo model.JoinRequestRepository,
+	profileChangeRepo model.GroupProfileChangeRepository, topicRepo model.GroupTopicRepository,
+	settingRepo model.ConversationSettingRepository, folders *folders.Evaluator, membership *membership.Service,
+	limiter *flood.Limiter, blocks *blocking.Service, hub *events.Hub) *Group {
 	return &Group{
-		groupChatRepo: groupChatRepo,
-		messageRepo:   messageRepo,
-		userGroupRepo: userGroupRepo,
-		repo:          repo,
+		limiter:           limiter,
+		blocks:            blocks,
+		membership:        membership,
+		joinRequestRepo:   joinRequestRepo,
+		profileChangeRepo: profileChangeRepo,
+		topicRepo:         topicRepo,
+		inviteRepo:        inviteRepo,
+		settingRepo:       settingRepo,
+		folders:           folders,
+		hub:               hub,
+		messageRepo:       messageRepo,
+		userGroupRepo:     userGroupRepo,
+		repo:              repo,
 	}
 }
 
+// member returns userID's membership in groupID, or nil when they are not in
+// the group.
+func (g *Group) member(c echo.Context, groupID, userID uint64) (*model.UserGroup, error) {
+	members, err := g.userGroupRepo.Get(c.Request().Context(), model.UserGroupInterface{
+		GroupID: &groupID,
+		UserID:  &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	return &members[0], nil
+}
+
+// authorize is the policy check every group handler goes through: it loads
+// the caller's membership and fails unless their role allows action.
+func (g *Group) authorize(c echo.Context, groupID, userID uint64, action model.GroupAction) (*model.UserGroup, error) {
+	member, err := g.member(c, groupID, userID)
+	if err != nil {
+		return nil, echo.ErrInternalServerError
+	}
+	if member == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "you are not part of this group")
+	}
+	if !member.Can(action) {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "your role in this group does not allow this")
+	}
+
+	return member, nil
+}
+
 func (g *Group) NewGroup(c echo.Context) error {
 	id := c.Get("userID")
 	creatorid, _ := id.(uint64)
@@ -40,10 +105,16 @@ func (g *Group) NewGroup(c echo.Context) error {
 
 	logrus.Warnln("uc", creatorid)
 
+	requiresApproval, err := formBool(c, "requiresapproval", false)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
 	groupID, err := g.repo.Create(c.Request().Context(), model.Group{
-		Creator:     creatorid,
-		Name:        name,
-		Description: c.FormValue("description"),
+		Creator:          creatorid,
+		Name:             name,
+		Description:      c.FormValue("description"),
+		RequiresApproval: requiresApproval,
 	})
 	if err != nil {
 		return echo.ErrInternalServerError
@@ -52,6 +123,15 @@ func (g *Group) NewGroup(c echo.Context) error {
 	if err = g.userGroupRepo.Create(c.Request().Context(), model.UserGroup{
 		GroupID: groupID,
 		UserID:  creatorid,
+		Role:    model.GroupOwner,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if err = g.membership.Announce(c.Request().Context(), groupID, model.SystemPayload{
+		Event:   model.SystemGroupCreated,
+		ActorID: creatorid,
+		New:     name,
 	}); err != nil {
 		return echo.ErrInternalServerError
 	}
@@ -62,11 +142,18 @@ func (g *Group) NewGroup(c echo.Context) error {
 }
 
 func (g *Group) GetGroupData(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
 	groupid, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
 	if err != nil {
 		return echo.ErrBadRequest
 	}
 
+	if _, err = g.authorize(c, groupid, userid, model.GroupRead); err != nil {
+		return err
+	}
+
 	group, users, err := g.userGroupRepo.GetGroupWithUserGroups(c.Request().Context(), groupid)
 	if err != nil {
 		return echo.ErrInternalServerError
@@ -95,6 +182,40 @@ func (g *Group) GetGroups(c echo.Context) error {
 		return echo.ErrInternalServerError
 	}
 
+	matched, err := folderFilter(c, g.folders, userID)
+	if err != nil {
+		return err
+	}
+
+	settings, err := loadSettings(c, g.settingRepo, userID)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	// A folder decides for itself which archived groups it shows.
+	archived := c.QueryParam("archived") == "true"
+	listed := groups[:0]
+	for _, group := range groups {
+		key := folders.Key{Type: model.TypeGP, ChatID: group.GroupID}
+		if matched == nil && settings[key].Archived != archived {
+			continue
+		}
+		if matched != nil && !matched[key] {
+			continue
+		}
+		listed = append(listed, group)
+	}
+	groups = listed
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		pi := settings[folders.Key{Type: model.TypeGP, ChatID: groups[i].GroupID}].PinOrder
+		pj := settings[folders.Key{Type: model.TypeGP, ChatID: groups[j].GroupID}].PinOrder
+		if pi == 0 || pj == 0 {
+			return pi != 0 && pj == 0
+		}
+		return pi < pj
+	})
+
 	if len(groups) == 0 {
 		return echo.ErrNotFound
 	}
@@ -104,30 +225,19 @@ func (g *Group) GetGroups(c echo.Context) error {
 
 func (g *Group) DeleteGroup(c echo.Context) error {
 	id := c.Get("userID")
-	creatorid, _ := id.(uint64)
+	userid, _ := id.(uint64)
 
 	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
 	if err != nil {
 		return echo.ErrBadRequest
 	}
 
-	groups, err := g.repo.Get(c.Request().Context(), model.GroupInterface{
-		ID:        &groupID,
-		CreatorID: &creatorid,
-	})
-	if err != nil {
-		return echo.ErrInternalServerError
-	}
-
-	if len(groups) == 0 {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"msg": "group not found",
-		})
+	if _, err = g.authorize(c, groupID, userid, model.GroupDelete); err != nil {
+		return err
 	}
 
 	if err = g.repo.Delete(c.Request().Context(), model.GroupInterface{
-		ID:        &groupID,
-		CreatorID: &creatorid,
+		ID: &groupID,
 	}); err != nil {
 		return echo.ErrInternalServerError
 	}
@@ -139,39 +249,51 @@ func (g *Group) DeleteGroup(c echo.Context) error {
 
 func (g *Group) AddUserToGroup(c echo.Context) error {
 	cid := c.Get("userID")
-	creatorid, _ := cid.(uint64)
+	userid, _ := cid.(uint64)
 
 	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
 	if err != nil {
 		return echo.ErrBadRequest
 	}
 
-	groups, err := g.repo.Get(c.Request().Context(), model.GroupInterface{
-		ID:        &groupID,
-		CreatorID: &creatorid,
-	})
+	if _, err = g.authorize(c, groupID, userid, model.GroupAddMembers); err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseUint(c.FormValue("id"), 10, 64)
 	if err != nil {
-		return echo.ErrInternalServerError
+		return echo.ErrBadRequest
 	}
 
-	if len(groups) == 0 {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"msg": "group not found",
+	existing, err := g.member(c, groupID, id)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if existing != nil {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"msg": "user is already a member of this group",
 		})
 	}
 
-	id, err := strconv.ParseUint(c.FormValue("id"), 10, 64)
-	if err != nil {
-		return echo.ErrBadRequest
+	if err = g.checkBan(c, groupID, id); err != nil {
+		return err
+	}
+	if err = checkBlocked(c, g.blocks, userid, id); err != nil {
+		return err
 	}
 
 	if err = g.userGroupRepo.Create(c.Request().Context(), model.UserGroup{
 		GroupID: groupID,
 		UserID:  id,
+		Role:    model.GroupMember,
 	}); err != nil {
 		return echo.ErrInternalServerError
 	}
 
+	if err = g.membership.Joined(c.Request().Context(), groupID, userid, id); err != nil {
+		return echo.ErrInternalServerError
+	}
+
 	return c.JSON(http.StatusCreated, map[string]string{
 		"msg": "user added",
 	})
@@ -179,36 +301,46 @@ func (g *Group) AddUserToGroup(c echo.Context) error {
 
 func (g *Group) DeleteUserFromGroup(c echo.Context) error {
 	cid := c.Get("userID")
-	creatorid, _ := cid.(uint64)
+	userid, _ := cid.(uint64)
 
 	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
 	if err != nil {
 		return echo.ErrBadRequest
 	}
 
-	groups, err := g.repo.Get(c.Request().Context(), model.GroupInterface{
-		ID:        &groupID,
-		CreatorID: &creatorid,
-	})
+	actor, err := g.authorize(c, groupID, userid, model.GroupRemoveMembers)
 	if err != nil {
-		return echo.ErrInternalServerError
+		return err
 	}
 
-	if len(groups) == 0 {
+	id, err := strconv.ParseUint(c.Param("userid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	target, err := g.member(c, groupID, id)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if target == nil {
 		return c.JSON(http.StatusNotFound, map[string]string{
-			"msg": "group not found",
+			"msg": "user is not a member of this group",
+		})
+	}
+	if !actor.Outranks(*target) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"msg": "you can not remove a member whose role is not below yours",
 		})
 	}
 
-	id, err := strconv.ParseUint(c.Param("userid"), 10, 64)
-	if err != nil {
-		return echo.ErrBadRequest
+	reason := c.QueryParam("reason")
+	if utf8.RuneCountInString(reason) > maxBanReason {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "removal reason is too long",
+		})
 	}
 
-	if err = g.userGroupRepo.Delete(c.Request().Context(), model.UserGroupInterface{
-		UserID:  &id,
-		GroupID: &groupID,
-	}); err != nil {
+	if err = g.membership.Remove(c.Request().Context(), groupID, userid, id, reason); err != nil {
 		return echo.ErrInternalServerError
 	}
 
@@ -217,6 +349,93 @@ func (g *Group) DeleteUserFromGroup(c echo.Context) error {
 	})
 }
 
+// sendGroupMessage posts a message from userID to topicID of groupID once
+// the sender's role, restrictions, the group rules, the topic and the
+// group's flood limits allow it. A refusal by the limiter comes back as
+// *flood.Error, anything else as an HTTP error.
+func (g *Group) sendGroupMessage(c echo.Context, groupID, topicID, userID uint64, kind model.ContentKind, content string,
+	payload json.RawMessage) (*flood.Error, error) {
+	sender, err := g.authorize(c, groupID, userID, model.GroupSendMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if sender.ReadOnly(now) {
+		return nil, echo.NewHTTPError(http.StatusForbidden, echo.Map{
+			"msg":   "you are read-only in this group",
+			"until": sender.ReadOnlyUntil,
+		})
+	}
+
+	kind, err = model.ValidateUserMessage(kind, content, payload)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, map[string]string{
+			"msg": err.Error(),
+		})
+	}
+
+	if kind != model.KindText && sender.MediaBlocked(now) {
+		return nil, echo.NewHTTPError(http.StatusForbidden, echo.Map{
+			"msg":   "you can only send text in this group",
+			"until": sender.NoMediaUntil,
+		})
+	}
+
+	groups, err := g.repo.Get(c.Request().Context(), model.GroupInterface{
+		ID: &groupID,
+	})
+	if err != nil || len(groups) == 0 {
+		return nil, echo.ErrInternalServerError
+	}
+
+	if sender.MustAcceptRules(groups[0]) {
+		return nil, echo.NewHTTPError(http.StatusForbidden, echo.Map{
+			"msg":   "accept the group rules before posting",
+			"rules": groups[0].Rules,
+		})
+	}
+
+	if topicID != model.GeneralTopic {
+		topic, err := g.topic(c, groupID, topicID)
+		if err != nil {
+			return nil, err
+		}
+		if topic.Closed && !sender.Can(model.GroupManageTopics) {
+			return nil, echo.NewHTTPError(http.StatusForbidden, map[string]string{
+				"msg": "this topic is closed",
+			})
+		}
+	}
+
+	// Admins and the owner are exempt from slow mode.
+	var slowMode time.Duration
+	if sender.Role != model.GroupOwner && sender.Role != model.GroupAdmin {
+		slowMode = time.Duration(groups[0].SlowModeSeconds) * time.Second
+	}
+
+	conv := flood.Conversation{Type: model.TypeGP, ChatID: groupID}
+	if refused := g.limiter.Allow(userID, conv, slowMode, kind, content, payload); refused != nil {
+		return refused, nil
+	}
+
+	if _, err = g.messageRepo.Create(c.Request().Context(), model.Message{
+		Content:  content,
+		Payload:  payload,
+		ChatID:   groupID,
+		TopicID:  topicID,
+		SenderID: userID,
+		Type:     model.TypeGP,
+		Kind:     kind,
+		IsRead:   "false",
+	}); err != nil {
+		g.limiter.Undo(userID, conv, kind, content, payload)
+		return nil, echo.ErrInternalServerError
+	}
+
+	return nil, nil
+}
+
 func (g *Group) NewGroupMessage(c echo.Context) error {
 	id := c.Get("userID")
 	uid, _ := id.(uint64)
@@ -235,48 +454,93 @@ func (g *Group) NewGroupMessage(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 
-	users, err := g.userGroupRepo.Get(c.Request().Context(), model.UserGroupInterface{
-		GroupID: &groupID,
-		UserID:  &uid,
-	})
-	if err != nil {
-		return echo.ErrInternalServerError
+	var topicID uint64
+	if v := c.FormValue("topicid"); v != "" {
+		if topicID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return echo.ErrBadRequest
+		}
 	}
 
-	if len(users) == 0 {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"msg": "you are not part of this group",
-		})
+	refused, err := g.sendGroupMessage(c, groupID, topicID, uid, model.ContentKind(c.FormValue("kind")), c.FormValue("content"),
+		json.RawMessage(c.FormValue("payload")))
+	if err != nil {
+		return err
 	}
-
-	messageContent := c.FormValue("content")
-	if messageContent == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"msg": "message body can not be empty",
-		})
+	if refused != nil {
+		return floodResponse(c, refused)
 	}
 
-	messageID, err := g.messageRepo.Create(c.Request().Context(), model.Message{
-		Content:  messageContent,
-		ChatID:   groupID,
-		SenderID: uid,
-		Type:     model.TypeGP,
-		IsRead:   "false",
+	return c.JSON(http.StatusCreated, map[string]string{
+		"msg": "message sent",
 	})
+}
+
+// NewGroupMessageWs is NewGroupMessage over a WebSocket. Every message is
+// answered with a JSON object: "msg" on success, plus "reason" and
+// "retryAfter" when the flood limits refused it.
+func (g *Group) NewGroupMessageWs(c echo.Context) error {
+	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
-		return echo.ErrInternalServerError
+		return err
 	}
+	defer ws.Close()
 
-	if err = g.groupChatRepo.Create(c.Request().Context(), model.GroupChat{
-		GroupID:   groupID,
-		MessageID: messageID,
-	}); err != nil {
-		return echo.ErrInternalServerError
+	id := c.Get("userID")
+	uid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
 	}
 
-	return c.JSON(http.StatusCreated, map[string]string{
-		"msg": "message sent",
-	})
+	for {
+		var incomingMessage struct {
+			Content string            `json:"content"`
+			Kind    model.ContentKind `json:"kind"`
+			Payload json.RawMessage   `json:"payload"`
+			TopicID uint64            `json:"topicID"`
+			Stat    string            `json:"stat"`
+		}
+
+		err = ws.ReadJSON(&incomingMessage)
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				return err
+			}
+			break
+		}
+
+		if incomingMessage.Stat == "exit" {
+			break
+		}
+
+		refused, err := g.sendGroupMessage(c, groupID, incomingMessage.TopicID, uid, incomingMessage.Kind, incomingMessage.Content,
+			incomingMessage.Payload)
+		var he *echo.HTTPError
+		switch {
+		case errors.As(err, &he):
+			if he.Code == http.StatusInternalServerError {
+				return err
+			}
+			if msg, ok := he.Message.(string); ok {
+				ws.WriteJSON(map[string]string{
+					"msg": msg,
+				})
+				continue
+			}
+			ws.WriteJSON(he.Message)
+		case err != nil:
+			return err
+		case refused != nil:
+			ws.WriteJSON(floodBody(refused))
+		default:
+			ws.WriteJSON(map[string]string{
+				"msg": "message sent",
+			})
+		}
+	}
+
+	return nil
 }
 
 func (g *Group) DeleteGroupMessage(c echo.Context) error {
@@ -288,23 +552,36 @@ func (g *Group) DeleteGroupMessage(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 
-	users, err := g.userGroupRepo.Get(c.Request().Context(), model.UserGroupInterface{
-		GroupID: &groupid,
-		UserID:  &uid,
+	member, err := g.authorize(c, groupid, uid, model.GroupRead)
+	if err != nil {
+		return err
+	}
+
+	messageid, err := strconv.ParseUint(c.Param("messageid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	chatType := model.TypeGP
+	messages, err := g.messageRepo.Get(c.Request().Context(), model.MessageInterface{
+		ID:     &messageid,
+		ChatID: &groupid,
+		Type:   &chatType,
 	})
 	if err != nil {
 		return echo.ErrInternalServerError
 	}
 
-	if len(users) == 0 {
+	if len(messages) == 0 {
 		return c.JSON(http.StatusNotFound, map[string]string{
-			"msg": "you are not part of this group",
+			"msg": "message not found",
 		})
 	}
 
-	messageid, err := strconv.ParseUint(c.Param("messageid"), 10, 64)
-	if err != nil {
-		return echo.ErrBadRequest
+	if messages[0].SenderID != uid && !member.Can(model.GroupDeleteMessages) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"msg": "you can only delete your own messages",
+		})
 	}
 
 	if err = g.messageRepo.Delete(c.Request().Context(), model.MessageInterface{
@@ -313,13 +590,6 @@ func (g *Group) DeleteGroupMessage(c echo.Context) error {
 		return echo.ErrInternalServerError
 	}
 
-	if err = g.groupChatRepo.Delete(c.Request().Context(), model.GroupChatInterface{
-		ID:      &messageid,
-		GroupID: &groupid,
-	}); err != nil {
-		return echo.ErrInternalServerError
-	}
-
 	return c.JSON(http.StatusCreated, map[string]string{
 		"msg": "message deleted",
 	})
@@ -334,18 +604,8 @@ func (g *Group) GetGroupMessages(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 
-	users, err := g.userGroupRepo.Get(c.Request().Context(), model.UserGroupInterface{
-		GroupID: &groupID,
-		UserID:  &uid,
-	})
-	if err != nil {
-		return echo.ErrInternalServerError
-	}
-
-	if len(users) == 0 {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"msg": "you are not part of this group",
-		})
+	if _, err = g.authorize(c, groupID, uid, model.GroupRead); err != nil {
+		return err
 	}
 
 	count, err := strconv.ParseUint(c.Param("count"), 10, 64)
@@ -354,11 +614,20 @@ func (g *Group) GetGroupMessages(c echo.Context) error {
 	}
 
 	chatType := model.TypeGP
-
-	messages, err := g.messageRepo.GetDto(c.Request().Context(), model.MessageInterface{
+	mi := model.MessageInterface{
 		ChatID: &groupID,
 		Type:   &chatType,
-	})
+	}
+
+	if v := c.QueryParam("topic"); v != "" {
+		topicID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return echo.ErrBadRequest
+		}
+		mi.TopicID = &topicID
+	}
+
+	messages, err := g.messageRepo.GetDto(c.Request().Context(), mi)
 	if err != nil {
 		return echo.ErrInternalServerError
 	}
@@ -371,9 +640,275 @@ func (g *Group) GetGroupMessages(c echo.Context) error {
 		count = uint64(len(messages))
 	}
 
+	if err = clearMarkedUnread(c, g.settingRepo, g.hub, uid, model.TypeGP, groupID); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	g.membership.Render(c.Request().Context(), messages[:count])
+
 	return c.JSON(http.StatusOK, messages[:count])
 }
 
+// SetAdmin promotes a member to admin with the submitted permission flags, or
+// updates the flags of an existing admin. DELETE demotes them to member.
+func (g *Group) SetAdmin(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	targetid, err := strconv.ParseUint(c.Param("userid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupManageAdmins); err != nil {
+		return err
+	}
+
+	target, err := g.member(c, groupID, targetid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if target == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "user is not a member of this group",
+		})
+	}
+	if target.Role == model.GroupOwner {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "the owner's role can not be changed",
+		})
+	}
+
+	update := model.UserGroup{
+		GroupID: groupID,
+		UserID:  targetid,
+		Role:    model.GroupMember,
+	}
+
+	if c.Request().Method == http.MethodPut {
+		perms := model.GroupPermissions{}
+		if target.Role == model.GroupAdmin {
+			perms = target.GroupPermissions
+		}
+
+		for _, flag := range []struct {
+			name  string
+			value *bool
+		}{
+			{"addmembers", &perms.CanAddMembers},
+			{"removemembers", &perms.CanRemoveMembers},
+			{"deletemessages", &perms.CanDeleteMessages},
+			{"pinmessages", &perms.CanPinMessages},
+			{"editinfo", &perms.CanEditInfo},
+			{"managetopics", &perms.CanManageTopics},
+		} {
+			if *flag.value, err = formBool(c, flag.name, *flag.value); err != nil {
+				return echo.ErrBadRequest
+			}
+		}
+
+		update.Role = model.GroupAdmin
+		update.GroupPermissions = perms
+	}
+
+	if err = g.userGroupRepo.SetRole(c.Request().Context(), update); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":    "member role updated",
+		"member": update,
+	})
+}
+
+// SetRestricted limits what a member may send. The "kind" form value picks
+// readonly (the default) or nomedia, and "until" or "duration" bounds it; a
+// read-only restriction without an end demotes the member to restricted.
+// DELETE lifts every restriction. Only members ranked above the target may
+// do either.
+func (g *Group) SetRestricted(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	targetid, err := strconv.ParseUint(c.Param("userid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	actor, err := g.authorize(c, groupID, userid, model.GroupRemoveMembers)
+	if err != nil {
+		return err
+	}
+
+	target, err := g.member(c, groupID, targetid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if target == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "user is not a member of this group",
+		})
+	}
+	if !actor.Outranks(*target) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"msg": "you can not restrict a member whose role is not below yours",
+		})
+	}
+
+	update := *target
+	if c.Request().Method == http.MethodDelete {
+		now := time.Now()
+		if target.Role != model.GroupRestricted && !target.ReadOnly(now) && !target.MediaBlocked(now) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "user is not restricted",
+			})
+		}
+		if update.Role == model.GroupRestricted {
+			update.Role = model.GroupMember
+		}
+		update.ReadOnlyUntil = nil
+		update.NoMediaUntil = nil
+	} else {
+		until, err := formUntil(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": err.Error(),
+			})
+		}
+
+		switch c.FormValue("kind") {
+		case "", "readonly":
+			if until == nil {
+				update.Role = model.GroupRestricted
+			}
+			update.ReadOnlyUntil = until
+		case "nomedia":
+			if until == nil {
+				until = &muteForever
+			}
+			update.NoMediaUntil = until
+		default:
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "kind must be readonly or nomedia",
+			})
+		}
+	}
+
+	if update.Role != target.Role {
+		if err = g.userGroupRepo.SetRole(c.Request().Context(), update); err != nil {
+			return echo.ErrInternalServerError
+		}
+	}
+	if err = g.userGroupRepo.SetRestrictions(c.Request().Context(), update); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":    "member restrictions updated",
+		"member": update,
+	})
+}
+
+func (g *Group) LeaveGroup(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	outcome, err := g.membership.Leave(c.Request().Context(), groupID, userid)
+	if errors.Is(err, membership.ErrNotMember) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "you are not part of this group",
+		})
+	}
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":     "you left the group",
+		"outcome": outcome,
+	})
+}
+
+func (g *Group) TransferOwnership(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	newOwnerID, err := strconv.ParseUint(c.FormValue("userid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupTransfer); err != nil {
+		return err
+	}
+
+	err = g.membership.Transfer(c.Request().Context(), groupID, userid, newOwnerID)
+	if errors.Is(err, membership.ErrSuccessorMember) || errors.Is(err, membership.ErrSelfTransfer) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": err.Error(),
+		})
+	}
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "ownership transferred",
+	})
+}
+
+// SetSlowMode sets the minimum number of seconds between two messages of
+// the same member; zero turns slow mode off. Admins are never slowed down.
+func (g *Group) SetSlowMode(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupEditInfo); err != nil {
+		return err
+	}
+
+	seconds, err := strconv.Atoi(c.FormValue("seconds"))
+	if err != nil || seconds < 0 || seconds > int(flood.MaxSlowMode/time.Second) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "seconds must be between 0 and 3600",
+		})
+	}
+
+	if err = g.repo.SetSlowMode(c.Request().Context(), groupID, seconds); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":             "group updated",
+		"slowModeSeconds": seconds,
+	})
+}
+
 func (g *Group) NewGroupHandler(gr *echo.Group) {
 	GroupsGroup := gr.Group("/groups")
 
@@ -381,9 +916,47 @@ func (g *Group) NewGroupHandler(gr *echo.Group) {
 	GroupsGroup.GET("/allgroups", g.GetGroups, mwares.JWTMiddleware)
 	GroupsGroup.GET("/:groupid", g.GetGroupData, mwares.JWTMiddleware)
 	GroupsGroup.DELETE("/:groupid", g.DeleteGroup, mwares.JWTMiddleware)
+	GroupsGroup.PATCH("/:groupid", g.UpdateGroup, mwares.JWTMiddleware)
+	GroupsGroup.GET("/:groupid/history", g.GetGroupHistory, mwares.JWTMiddleware)
+	GroupsGroup.GET("/:groupid/members", g.GetGroupMembers, mwares.JWTMiddleware)
+	GroupsGroup.GET("/:groupid/avatar", g.GetGroupAvatar, mwares.JWTMiddleware)
 	GroupsGroup.POST("/:groupid", g.AddUserToGroup, mwares.JWTMiddleware)
 	GroupsGroup.DELETE("/:groupid/:userid", g.DeleteUserFromGroup, mwares.JWTMiddleware)
+	GroupsGroup.POST("/:groupid/leave", g.LeaveGroup, mwares.JWTMiddleware)
+	GroupsGroup.POST("/:groupid/transfer", g.TransferOwnership, mwares.JWTMiddleware)
+	GroupsGroup.PUT("/:groupid/admins/:userid", g.SetAdmin, mwares.JWTMiddleware)
+	GroupsGroup.DELETE("/:groupid/admins/:userid", g.SetAdmin, mwares.JWTMiddleware)
+	GroupsGroup.PUT("/:groupid/restricted/:userid", g.SetRestricted, mwares.JWTMiddleware)
+	GroupsGroup.DELETE("/:groupid/restricted/:userid", g.SetRestricted, mwares.JWTMiddleware)
+	GroupsGroup.GET("/:groupid/bans", g.GetBans, mwares.JWTMiddleware)
+	GroupsGroup.PUT("/:groupid/bans/:userid", g.BanMember, mwares.JWTMiddleware)
+	GroupsGroup.DELETE("/:groupid/bans/:userid", g.UnbanMember, mwares.JWTMiddleware)
+	GroupsGroup.POST("/:groupid/invites", g.NewInvite, mwares.JWTMiddleware)
+	GroupsGroup.GET("/:groupid/invites", g.GetInvites, mwares.JWTMiddleware)
+	GroupsGroup.GET("/:groupid/invites/:inviteid", g.GetInvite, mwares.JWTMiddleware)
+	GroupsGroup.DELETE("/:groupid/invites/:inviteid", g.RevokeInvite, mwares.JWTMiddleware)
+	GroupsGroup.PUT("/:groupid/approval", g.SetRequiresApproval, mwares.JWTMiddleware)
+	GroupsGroup.PUT("/:groupid/slowmode", g.SetSlowMode, mwares.JWTMiddleware)
+	GroupsGroup.GET("/:groupid/rules", g.GetRules, mwares.JWTMiddleware)
+	GroupsGroup.PUT("/:groupid/rules", g.SetRules, mwares.JWTMiddleware)
+	GroupsGroup.POST("/:groupid/rules/accept", g.AcceptRules, mwares.JWTMiddleware)
+	GroupsGroup.PUT("/:groupid/forum", g.SetForumMode, mwares.JWTMiddleware)
+	GroupsGroup.GET("/:groupid/topics", g.GetTopics, mwares.JWTMiddleware)
+	GroupsGroup.POST("/:groupid/topics", g.NewTopic, mwares.JWTMiddleware)
+	GroupsGroup.PATCH("/:groupid/topics/:topicid", g.UpdateTopic, mwares.JWTMiddleware)
+	GroupsGroup.DELETE("/:groupid/topics/:topicid", g.DeleteTopic, mwares.JWTMiddleware)
+	GroupsGroup.PUT("/:groupid/topics/:topicid/pin", g.PinTopicMessage, mwares.JWTMiddleware)
+	GroupsGroup.DELETE("/:groupid/topics/:topicid/pin", g.PinTopicMessage, mwares.JWTMiddleware)
+	GroupsGroup.POST("/:groupid/requests", g.NewJoinRequest, mwares.JWTMiddleware)
+	GroupsGroup.GET("/:groupid/requests", g.GetJoinRequests, mwares.JWTMiddleware)
+	GroupsGroup.POST("/:groupid/requests/:requestid/approve", g.ApproveJoinRequest, mwares.JWTMiddleware)
+	GroupsGroup.POST("/:groupid/requests/:requestid/decline", g.DeclineJoinRequest, mwares.JWTMiddleware)
+
+	invitesGroup := gr.Group("/invites")
+	invitesGroup.GET("/:token", g.PreviewInvite, mwares.JWTMiddleware)
+	invitesGroup.POST("/:token/join", g.JoinByInvite, mwares.JWTMiddleware)
 	GroupsGroup.POST("/:groupid/message/:userid", g.NewGroupMessage, mwares.JWTMiddleware)
+	GroupsGroup.GET("/:groupid/message/ws", g.NewGroupMessageWs, mwares.JWTMiddleware)
 	GroupsGroup.DELETE("/:groupid/message/:messageid", g.DeleteGroupMessage, mwares.JWTMiddleware)
 	GroupsGroup.GET("/:groupid/message/:count", g.GetGroupMessages, mwares.JWTMiddleware)
 }
@@ -0,0 +1,92 @@
+package endpoints
+
+import (
+	"backend/internal/importer"
+	"backend/internal/mwares"
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+// maxImportSize caps the whole upload, export and form fields together.
+const maxImportSize = 64 << 20
+
+type Import struct {
+	importer *importer.Importer
+}
+
+func NewImport(importer *importer.Importer) *Import {
+	return &Import{
+		importer: importer,
+	}
+}
+
+// ImportTelegram imports a Telegram export on behalf of the caller. mapping
+// is a JSON object from each participant's Telegram from_id to the
+// username or phone of their account here; messages of participants left
+// out are skipped.
+func (im *Import) ImportTelegram(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, maxImportSize)
+
+	fh, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{
+			"msg": "telegram export is too large",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "telegram result.json file is required",
+		})
+	}
+
+	mapping := make(map[string]string)
+	if v := c.FormValue("mapping"); v != "" {
+		if err = json.Unmarshal([]byte(v), &mapping); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "mapping must be a JSON object of telegram from_id to username or phone",
+			})
+		}
+	}
+
+	file, err := fh.Open()
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	defer file.Close()
+
+	dryRun := c.FormValue("dryrun") == "true"
+	report, err := im.importer.Telegram(c.Request().Context(), userid, file, mapping, dryRun)
+	if errors.Is(err, importer.ErrNotParticipant) {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"msg":    err.Error(),
+			"report": report,
+		})
+	}
+	if errors.Is(err, importer.ErrInvalidExport) || errors.Is(err, importer.ErrUnsupportedChat) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": err.Error(),
+		})
+	}
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+
+	return c.JSON(status, report)
+}
+
+func (im *Import) NewImportHandler(g *echo.Group) {
+	importGroup := g.Group("/import")
+
+	importGroup.POST("/telegram", im.ImportTelegram, mwares.JWTMiddleware)
+}
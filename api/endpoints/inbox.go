@@ -0,0 +1,159 @@
+package endpoints
+
+import (
+	"backend/internal/blocking"
+	"backend/internal/model"
+	"backend/internal/mwares"
+	"encoding/base64"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultInboxLimit = 30
+	maxInboxLimit     = 100
+)
+
+type Inbox struct {
+	repo   model.InboxRepository
+	blocks *blocking.Service
+}
+
+func NewInbox(repo model.InboxRepository, blocks *blocking.Service) *Inbox {
+	return &Inbox{
+		blocks: blocks,
+		repo:   repo,
+	}
+}
+
+// hideBlockers blanks the picture and presence of peers who blocked userID.
+func (in *Inbox) hideBlockers(c echo.Context, userID uint64, items []model.InboxItem) error {
+	blockers, err := in.blocks.BlockedBy(c.Request().Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		if peer := items[i].Peer; peer != nil && blockers[peer.UserID] {
+			blocking.HideProfile(&peer.ProfilePicture, &peer.IsActive, &peer.LastSeen)
+		}
+	}
+
+	return nil
+}
+
+func encodeInboxCursor(cursor model.InboxCursor) string {
+	raw := fmt.Sprintf("%d:%s:%d", cursor.ActivityAt.UnixNano(), cursor.Type, cursor.ChatID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeInboxCursor(s string) (*model.InboxCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	chatID, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.InboxCursor{
+		ActivityAt: time.Unix(0, nanos).UTC(),
+		Type:       model.MessageType(parts[1]),
+		ChatID:     chatID,
+	}, nil
+}
+
+// GetInbox lists the user's chats and groups together, most recently active
+// first. Pinned conversations are returned separately on the first page only,
+// so they never shift the cursor of the main list.
+func (in *Inbox) GetInbox(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	iq := model.InboxQuery{
+		UserID:   userid,
+		Archived: c.QueryParam("archived") == "true",
+		Limit:    defaultInboxLimit,
+	}
+
+	if v := c.QueryParam("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return echo.ErrBadRequest
+		}
+		if limit > maxInboxLimit {
+			limit = maxInboxLimit
+		}
+		iq.Limit = limit
+	}
+
+	if v := c.QueryParam("cursor"); v != "" {
+		cursor, err := decodeInboxCursor(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "invalid cursor",
+			})
+		}
+		iq.Cursor = cursor
+	}
+
+	pinned := []model.InboxItem{}
+	if iq.Cursor == nil && !iq.Archived {
+		pq := iq
+		pq.Pinned = true
+		pq.Limit = model.MaxPinnedConversations
+
+		items, err := in.repo.List(c.Request().Context(), pq)
+		if err != nil {
+			return echo.ErrInternalServerError
+		}
+		pinned = items
+	}
+
+	items, err := in.repo.List(c.Request().Context(), iq)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if err = in.hideBlockers(c, userid, pinned); err != nil {
+		return echo.ErrInternalServerError
+	}
+	if err = in.hideBlockers(c, userid, items); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	nextCursor := ""
+	if len(items) == iq.Limit {
+		last := items[len(items)-1]
+		nextCursor = encodeInboxCursor(model.InboxCursor{
+			ActivityAt: last.ActivityAt,
+			Type:       last.Type,
+			ChatID:     last.ChatID,
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"pinned":        pinned,
+		"conversations": items,
+		"nextCursor":    nextCursor,
+	})
+}
+
+func (in *Inbox) NewInboxHandler(g *echo.Group) {
+	g.GET("/inbox", in.GetInbox, mwares.JWTMiddleware)
+}
@@ -0,0 +1,309 @@
+package endpoints
+
+import (
+	"backend/internal/model"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+)
+
+const (
+	EventJoinRequest = "group.join_request"
+
+	maxJoinRequestMessage = 500
+)
+
+// notifyJoinRequest sends the request's current state to the requester and
+// to everyone in the group who may decide on it.
+func (g *Group) notifyJoinRequest(c echo.Context, request model.JoinRequestDTO) error {
+	members, err := g.userGroupRepo.Get(c.Request().Context(), model.UserGroupInterface{
+		GroupID: &request.GroupID,
+	})
+	if err != nil {
+		return err
+	}
+
+	recipients := []uint64{request.UserID}
+	for _, member := range members {
+		if member.UserID != request.UserID && member.Can(model.GroupAddMembers) {
+			recipients = append(recipients, member.UserID)
+		}
+	}
+
+	g.hub.PublishMany(recipients, EventJoinRequest, request)
+	return nil
+}
+
+// fileJoinRequest queues userID for approval, or returns their request that
+// is already pending. Its errors are ready to be returned by the handler.
+func (g *Group) fileJoinRequest(c echo.Context, groupID, userID uint64, message string, inviteID uint64) (*model.JoinRequestDTO, bool, error) {
+	if utf8.RuneCountInString(message) > maxJoinRequestMessage {
+		return nil, false, echo.NewHTTPError(http.StatusBadRequest, echo.Map{
+			"msg": "join request message is too long",
+		})
+	}
+
+	status := model.JoinPending
+	pending, err := g.joinRequestRepo.Get(c.Request().Context(), model.JoinRequestInterface{
+		GroupID: &groupID,
+		UserID:  &userID,
+		Status:  &status,
+	})
+	if err != nil {
+		return nil, false, echo.ErrInternalServerError
+	}
+	if len(pending) != 0 {
+		return &pending[0], false, nil
+	}
+
+	request := model.JoinRequest{
+		GroupID:  groupID,
+		UserID:   userID,
+		Message:  message,
+		InviteID: inviteID,
+		Status:   model.JoinPending,
+	}
+
+	requestID, err := g.joinRequestRepo.Create(c.Request().Context(), request)
+	if err != nil {
+		return nil, false, echo.ErrInternalServerError
+	}
+
+	requests, err := g.joinRequestRepo.Get(c.Request().Context(), model.JoinRequestInterface{
+		ID: &requestID,
+	})
+	if err != nil || len(requests) == 0 {
+		return nil, false, echo.ErrInternalServerError
+	}
+
+	if err = g.notifyJoinRequest(c, requests[0]); err != nil {
+		return nil, false, echo.ErrInternalServerError
+	}
+
+	return &requests[0], true, nil
+}
+
+func (g *Group) NewJoinRequest(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	groups, err := g.repo.Get(c.Request().Context(), model.GroupInterface{
+		ID: &groupID,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if len(groups) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "group not found",
+		})
+	}
+	if !groups[0].RequiresApproval {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "this group does not take join requests",
+		})
+	}
+
+	existing, err := g.member(c, groupID, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if existing != nil {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"msg": "you are already a member of this group",
+		})
+	}
+
+	if err = g.checkBan(c, groupID, userid); err != nil {
+		return err
+	}
+
+	request, created, err := g.fileJoinRequest(c, groupID, userid, c.FormValue("message"), 0)
+	if err != nil {
+		return err
+	}
+	if !created {
+		return c.JSON(http.StatusConflict, echo.Map{
+			"msg":     "you already have a pending join request",
+			"request": request,
+		})
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"msg":     "join request sent",
+		"request": request,
+	})
+}
+
+func (g *Group) GetJoinRequests(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupAddMembers); err != nil {
+		return err
+	}
+
+	status := model.JoinPending
+	if v := c.QueryParam("status"); v != "" {
+		status = model.JoinRequestStatus(v)
+		if status != model.JoinPending && status != model.JoinApproved && status != model.JoinDeclined {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "invalid join request status",
+			})
+		}
+	}
+
+	requests, err := g.joinRequestRepo.Get(c.Request().Context(), model.JoinRequestInterface{
+		GroupID: &groupID,
+		Status:  &status,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, requests)
+}
+
+func (g *Group) decideJoinRequest(c echo.Context, status model.JoinRequestStatus) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	requestID, err := strconv.ParseUint(c.Param("requestid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupAddMembers); err != nil {
+		return err
+	}
+
+	requests, err := g.joinRequestRepo.Get(c.Request().Context(), model.JoinRequestInterface{
+		ID:      &requestID,
+		GroupID: &groupID,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if len(requests) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "join request not found",
+		})
+	}
+
+	if status == model.JoinApproved {
+		if err = g.checkBan(c, groupID, requests[0].UserID); err != nil {
+			return err
+		}
+	}
+
+	ok, err := g.joinRequestRepo.Decide(c.Request().Context(), requestID, status, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if !ok {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"msg": "join request was already decided",
+		})
+	}
+
+	request := requests[0]
+	if status == model.JoinApproved {
+		existing, err := g.member(c, groupID, request.UserID)
+		if err != nil {
+			return echo.ErrInternalServerError
+		}
+
+		if existing == nil {
+			if err = g.userGroupRepo.Create(c.Request().Context(), model.UserGroup{
+				GroupID:  groupID,
+				UserID:   request.UserID,
+				Role:     model.GroupMember,
+				InviteID: request.InviteID,
+			}); err != nil {
+				return echo.ErrInternalServerError
+			}
+
+			// The link may have expired while the request waited; the
+			// approval stands either way, it only counts when it can.
+			if request.InviteID != 0 {
+				if _, err = g.inviteRepo.Use(c.Request().Context(), request.InviteID); err != nil {
+					return echo.ErrInternalServerError
+				}
+			}
+
+			if err = g.membership.Joined(c.Request().Context(), groupID, request.UserID, request.UserID); err != nil {
+				return echo.ErrInternalServerError
+			}
+		}
+	}
+
+	decided, err := g.joinRequestRepo.Get(c.Request().Context(), model.JoinRequestInterface{
+		ID: &requestID,
+	})
+	if err != nil || len(decided) == 0 {
+		return echo.ErrInternalServerError
+	}
+
+	if err = g.notifyJoinRequest(c, decided[0]); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":     "join request " + string(status),
+		"request": decided[0],
+	})
+}
+
+func (g *Group) ApproveJoinRequest(c echo.Context) error {
+	return g.decideJoinRequest(c, model.JoinApproved)
+}
+
+func (g *Group) DeclineJoinRequest(c echo.Context) error {
+	return g.decideJoinRequest(c, model.JoinDeclined)
+}
+
+// SetRequiresApproval switches whether joining the group needs an admin's
+// approval.
+func (g *Group) SetRequiresApproval(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	groupID, err := strconv.ParseUint(c.Param("groupid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if _, err = g.authorize(c, groupID, userid, model.GroupEditInfo); err != nil {
+		return err
+	}
+
+	required, err := strconv.ParseBool(c.FormValue("required"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if err = g.repo.SetRequiresApproval(c.Request().Context(), groupID, required); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"msg":              "group updated",
+		"requiresApproval": required,
+	})
+}
@@ -0,0 +1,23 @@
+package endpoints
+
+import (
+	"backend/internal/flood"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+)
+
+func floodBody(refused *flood.Error) echo.Map {
+	return echo.Map{
+		"msg":        refused.Error(),
+		"reason":     refused.Reason,
+		"retryAfter": refused.RetryAfterSeconds(),
+	}
+}
+
+// floodResponse answers a message the limiter refused with 429 and a
+// Retry-After header; the body repeats the wait in seconds.
+func floodResponse(c echo.Context, refused *flood.Error) error {
+	c.Response().Header().Set("Retry-After", strconv.Itoa(refused.RetryAfterSeconds()))
+	return c.JSON(http.StatusTooManyRequests, floodBody(refused))
+}
@@ -0,0 +1,309 @@
+package endpoints
+
+import (
+	"backend/internal/model"
+	"backend/internal/mwares"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxBookmarkNote    = 1000
+	maxBookmarkTags    = 255
+	maxBookmarkContent = 5000
+)
+
+type Saved struct {
+	repo        model.BookmarkRepository
+	messageRepo model.MessageRepository
+	access      *conversationAccess
+}
+
+func NewSaved(repo model.BookmarkRepository, messageRepo model.MessageRepository, chatRepo model.UserChatRepository,
+	userGroupRepo model.UserGroupRepository, userChannelRepo model.UserChannelRepository) *Saved {
+	return &Saved{
+		access:      newConversationAccess(chatRepo, userGroupRepo, userChannelRepo),
+		messageRepo: messageRepo,
+		repo:        repo,
+	}
+}
+
+func normalizeTags(raw string) string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(raw, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return strings.Join(tags, ",")
+}
+
+// checkBookmark refuses a note, tag list or note content longer than its
+// column, with an error ready to be returned by the handler.
+func checkBookmark(bookmark model.Bookmark) error {
+	var msg string
+	switch {
+	case utf8.RuneCountInString(bookmark.Note) > maxBookmarkNote:
+		msg = "bookmark note is too long"
+	case utf8.RuneCountInString(bookmark.Tags) > maxBookmarkTags:
+		msg = "bookmark tags are too long"
+	case utf8.RuneCountInString(bookmark.Content) > maxBookmarkContent:
+		msg = "note content is too long"
+	default:
+		return nil
+	}
+
+	return echo.NewHTTPError(http.StatusBadRequest, map[string]string{
+		"msg": msg,
+	})
+}
+
+func (s *Saved) NewBookmark(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	messageid, err := strconv.ParseUint(c.FormValue("messageid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	messages, err := s.messageRepo.Get(c.Request().Context(), model.MessageInterface{
+		ID: &messageid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if len(messages) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "message not found",
+		})
+	}
+
+	message := messages[0]
+	ok, err := s.access.canRead(c.Request().Context(), userid, message.ChatID, message.Type)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "can not access this message",
+		})
+	}
+
+	bookmarkKind := model.KindBookmark
+	old, err := s.repo.Get(c.Request().Context(), model.BookmarkInterface{
+		UserID:    &userid,
+		MessageID: &messageid,
+		Kind:      &bookmarkKind,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if len(old) != 0 {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"msg": "message already saved",
+		})
+	}
+
+	bookmark := model.Bookmark{
+		UserID:      userid,
+		Kind:        model.KindBookmark,
+		MessageID:   message.MessageID,
+		ChatID:      message.ChatID,
+		Type:        message.Type,
+		SenderID:    message.SenderID,
+		MessageKind: message.Kind,
+		Content:     message.Content,
+		Payload:     message.Payload,
+		Note:        c.FormValue("note"),
+		Tags:        normalizeTags(c.FormValue("tags")),
+	}
+	if err = checkBookmark(bookmark); err != nil {
+		return err
+	}
+
+	bookmarkID, err := s.repo.Create(c.Request().Context(), bookmark)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"msg":        "message saved",
+		"bookmarkID": bookmarkID,
+	})
+}
+
+func (s *Saved) NewNote(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	content := c.FormValue("content")
+	if content == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "note content can not be empty",
+		})
+	}
+
+	note := model.Bookmark{
+		UserID:      userid,
+		Kind:        model.KindNote,
+		SenderID:    userid,
+		MessageKind: model.KindText,
+		Content:     content,
+		Tags:        normalizeTags(c.FormValue("tags")),
+	}
+	if err := checkBookmark(note); err != nil {
+		return err
+	}
+
+	bookmarkID, err := s.repo.Create(c.Request().Context(), note)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"msg":        "note saved",
+		"bookmarkID": bookmarkID,
+	})
+}
+
+func (s *Saved) GetBookmarks(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	bi := model.BookmarkInterface{
+		UserID: &userid,
+	}
+
+	if q := c.QueryParam("q"); q != "" {
+		bi.Query = &q
+	}
+	if tag := normalizeTags(c.QueryParam("tag")); tag != "" {
+		bi.Tag = &tag
+	}
+	if kind := c.QueryParam("kind"); kind != "" {
+		bookmarkKind := model.BookmarkKind(kind)
+		bi.Kind = &bookmarkKind
+	}
+
+	bookmarks, err := s.repo.Get(c.Request().Context(), bi)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, bookmarks)
+}
+
+func (s *Saved) UpdateBookmark(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	bookmarkid, err := strconv.ParseUint(c.Param("bookmarkid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	bookmarks, err := s.repo.Get(c.Request().Context(), model.BookmarkInterface{
+		ID:     &bookmarkid,
+		UserID: &userid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if len(bookmarks) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "bookmark not found",
+		})
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	// Fields that are not sent keep their value; sending one empty clears
+	// it.
+	update := bookmarks[0].Bookmark
+	if _, ok := form["note"]; ok {
+		update.Note = c.FormValue("note")
+	}
+	if _, ok := form["tags"]; ok {
+		update.Tags = normalizeTags(c.FormValue("tags"))
+	}
+	if _, ok := form["content"]; ok && update.Kind == model.KindNote {
+		update.Content = c.FormValue("content")
+		if update.Content == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "note content can not be empty",
+			})
+		}
+	}
+
+	if err = checkBookmark(update); err != nil {
+		return err
+	}
+
+	if err = s.repo.Update(c.Request().Context(), update); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "bookmark updated",
+	})
+}
+
+func (s *Saved) DeleteBookmark(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	bookmarkid, err := strconv.ParseUint(c.Param("bookmarkid"), 10, 64)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	bookmarks, err := s.repo.Get(c.Request().Context(), model.BookmarkInterface{
+		ID:     &bookmarkid,
+		UserID: &userid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	if len(bookmarks) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "bookmark not found",
+		})
+	}
+
+	if err = s.repo.Delete(c.Request().Context(), model.BookmarkInterface{
+		ID:     &bookmarkid,
+		UserID: &userid,
+	}); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "bookmark deleted",
+	})
+}
+
+func (s *Saved) NewSavedHandler(g *echo.Group) {
+	savedGroup := g.Group("/saved")
+
+	savedGroup.GET("", s.GetBookmarks, mwares.JWTMiddleware)
+	savedGroup.POST("", s.NewBookmark, mwares.JWTMiddleware)
+	savedGroup.POST("/notes", s.NewNote, mwares.JWTMiddleware)
+	savedGroup.PATCH("/:bookmarkid", s.UpdateBookmark, mwares.JWTMiddleware)
+	savedGroup.DELETE("/:bookmarkid", s.DeleteBookmark, mwares.JWTMiddleware)
+}
@@ -0,0 +1,174 @@
+package endpoints
+
+import (
+	"backend/internal/model"
+	"backend/internal/mwares"
+	"encoding/base64"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type Search struct {
+	messageRepo model.MessageRepository
+}
+
+func NewSearch(messageRepo model.MessageRepository) *Search {
+	return &Search{
+		messageRepo: messageRepo,
+	}
+}
+
+func encodeSearchCursor(cursor model.SearchCursor) string {
+	raw := fmt.Sprintf("%s:%d", strconv.FormatFloat(float64(cursor.Rank), 'g', -1, 32), cursor.MessageID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSearchCursor(s string) (*model.SearchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var rank float32
+	var messageID uint64
+	if _, err = fmt.Sscanf(string(raw), "%g:%d", &rank, &messageID); err != nil {
+		return nil, err
+	}
+
+	return &model.SearchCursor{
+		Rank:      rank,
+		MessageID: messageID,
+	}, nil
+}
+
+func (s *Search) SearchMessages(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	query := c.QueryParam("q")
+	if query == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "search query can not be empty",
+		})
+	}
+
+	ms := model.MessageSearch{
+		Query:  query,
+		UserID: userid,
+		Limit:  defaultSearchLimit,
+	}
+
+	if v := c.QueryParam("chatid"); v != "" {
+		chatid, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return echo.ErrBadRequest
+		}
+		ms.ChatID = &chatid
+	}
+
+	if v := c.QueryParam("sender"); v != "" {
+		senderid, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return echo.ErrBadRequest
+		}
+		ms.SenderID = &senderid
+	}
+
+	if v := c.QueryParam("type"); v != "" {
+		chatType := model.MessageType(v)
+		if chatType != model.TypePV && chatType != model.TypeGP && chatType != model.TypeCH {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "invalid message type",
+			})
+		}
+		ms.Type = &chatType
+	}
+
+	// Chat, group and channel ids overlap; an id alone does not say which
+	// conversation is meant.
+	if ms.ChatID != nil && ms.Type == nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "chatid needs a type",
+		})
+	}
+
+	if v := c.QueryParam("kind"); v != "" {
+		kind := model.ContentKind(v)
+		if !kind.Valid() {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "invalid message kind",
+			})
+		}
+		ms.Kind = &kind
+	}
+
+	if v := c.QueryParam("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return echo.ErrBadRequest
+		}
+		ms.From = &from
+	}
+
+	if v := c.QueryParam("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return echo.ErrBadRequest
+		}
+		ms.To = &to
+	}
+
+	if v := c.QueryParam("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return echo.ErrBadRequest
+		}
+		if limit > maxSearchLimit {
+			limit = maxSearchLimit
+		}
+		ms.Limit = limit
+	}
+
+	if v := c.QueryParam("cursor"); v != "" {
+		cursor, err := decodeSearchCursor(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "invalid cursor",
+			})
+		}
+		ms.Cursor = cursor
+	}
+
+	results, err := s.messageRepo.Search(c.Request().Context(), ms)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	nextCursor := ""
+	if len(results) == ms.Limit {
+		last := results[len(results)-1]
+		nextCursor = encodeSearchCursor(model.SearchCursor{
+			Rank:      last.Rank,
+			MessageID: last.MessageID,
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"results":    results,
+		"nextCursor": nextCursor,
+	})
+}
+
+func (s *Search) NewSearchHandler(g *echo.Group) {
+	searchGroup := g.Group("/search")
+
+	searchGroup.GET("/messages", s.SearchMessages, mwares.JWTMiddleware)
+}
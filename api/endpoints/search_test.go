@@ -0,0 +1,58 @@
+package endpoints
+
+import (
+	"backend/internal/model"
+	"encoding/base64"
+	"testing"
+)
+
+func TestSearchCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor model.SearchCursor
+	}{
+		{name: "zero", cursor: model.SearchCursor{}},
+		{name: "typical", cursor: model.SearchCursor{Rank: 0.0607927, MessageID: 42}},
+		{name: "tiny rank", cursor: model.SearchCursor{Rank: 1e-20, MessageID: 7}},
+		{name: "large id", cursor: model.SearchCursor{Rank: 1, MessageID: 1<<64 - 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeSearchCursor(encodeSearchCursor(tt.cursor))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if *got != tt.cursor {
+				t.Fatalf("got %+v, want %+v", *got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeSearchCursorInvalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{name: "empty", in: ""},
+		{name: "not base64", in: "***"},
+		{name: "padded base64", in: base64.URLEncoding.EncodeToString([]byte("0.5:1"))},
+		{name: "no separator", in: encode("0.5")},
+		{name: "bad rank", in: encode("x:1")},
+		{name: "bad id", in: encode("0.5:x")},
+		{name: "negative id", in: encode("0.5:-1")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cursor, err := decodeSearchCursor(tt.in); err == nil {
+				t.Fatalf("decoded %+v, want an error", *cursor)
+			}
+		})
+	}
+}
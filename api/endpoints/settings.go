@@ -0,0 +1,300 @@
+package endpoints
+
+import (
+	"backend/internal/events"
+	"backend/internal/folders"
+	"backend/internal/model"
+	"backend/internal/mwares"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const EventConversationSettings = "conversation.settings"
+
+var muteForever = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+type ConversationSettings struct {
+	repo   model.ConversationSettingRepository
+	access *conversationAccess
+	hub    *events.Hub
+}
+
+func NewConversationSettings(repo model.ConversationSettingRepository, chatRepo model.UserChatRepository,
+	userGroupRepo model.UserGroupRepository, userChannelRepo model.UserChannelRepository, hub *events.Hub) *ConversationSettings {
+	return &ConversationSettings{
+		access: newConversationAccess(chatRepo, userGroupRepo, userChannelRepo),
+		repo:   repo,
+		hub:    hub,
+	}
+}
+
+// parseConversationKey reads a conversation written as "TYPE:ID", e.g. "GP:12".
+func parseConversationKey(raw string) (folders.Key, error) {
+	parts := strings.SplitN(strings.TrimSpace(raw), ":", 2)
+	if len(parts) != 2 {
+		return folders.Key{}, errors.New("conversations must be written as TYPE:ID")
+	}
+
+	chatType := model.MessageType(strings.ToUpper(parts[0]))
+	if chatType != model.TypePV && chatType != model.TypeGP && chatType != model.TypeCH {
+		return folders.Key{}, errors.New("unknown conversation type")
+	}
+
+	chatID, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return folders.Key{}, err
+	}
+
+	return folders.Key{Type: chatType, ChatID: chatID}, nil
+}
+
+// loadSettings returns the settings userID has for each conversation. Missing
+// conversations simply have the zero value.
+func loadSettings(c echo.Context, repo model.ConversationSettingRepository, userID uint64) (map[folders.Key]model.ConversationSetting, error) {
+	settings, err := repo.Get(c.Request().Context(), model.ConversationSettingInterface{
+		UserID: &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[folders.Key]model.ConversationSetting, len(settings))
+	for _, setting := range settings {
+		byKey[folders.Key{Type: setting.Type, ChatID: setting.ChatID}] = setting
+	}
+
+	return byKey, nil
+}
+
+// clearMarkedUnread drops the manual unread mark once the user reads the
+// conversation and tells their other devices.
+func clearMarkedUnread(c echo.Context, repo model.ConversationSettingRepository, hub *events.Hub, userID uint64,
+	chatType model.MessageType, chatID uint64) error {
+	settings, err := repo.Get(c.Request().Context(), model.ConversationSettingInterface{
+		UserID: &userID,
+		Type:   &chatType,
+		ChatID: &chatID,
+	})
+	if err != nil || len(settings) == 0 || !settings[0].MarkedUnread {
+		return err
+	}
+
+	setting := settings[0]
+	setting.MarkedUnread = false
+	if err = repo.Save(c.Request().Context(), setting); err != nil {
+		return err
+	}
+
+	hub.Publish(userID, EventConversationSettings, setting)
+	return nil
+}
+
+// unpinStale unpins the conversations in all that userID can no longer read,
+// such as chats they deleted or groups they left, so they stop counting
+// toward the pin limit and need not be listed when reordering.
+func (cs *ConversationSettings) unpinStale(c echo.Context, userID uint64, all map[folders.Key]model.ConversationSetting) error {
+	for key, setting := range all {
+		if !setting.Pinned() {
+			continue
+		}
+
+		ok, err := cs.access.canRead(c.Request().Context(), userID, key.ChatID, key.Type)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+
+		setting.PinOrder = 0
+		if err = cs.repo.Save(c.Request().Context(), setting); err != nil {
+			return err
+		}
+		all[key] = setting
+	}
+
+	return nil
+}
+
+func (cs *ConversationSettings) GetSettings(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	settings, err := cs.repo.Get(c.Request().Context(), model.ConversationSettingInterface{
+		UserID: &userid,
+	})
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, settings)
+}
+
+func (cs *ConversationSettings) UpdateSettings(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	key, err := parseConversationKey(c.Param("type") + ":" + c.Param("chatid"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	ok, err := cs.access.canRead(c.Request().Context(), userid, key.ChatID, key.Type)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"msg": "can not access this conversation",
+		})
+	}
+
+	all, err := loadSettings(c, cs.repo, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if err = cs.unpinStale(c, userid, all); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	setting, exists := all[key]
+	if !exists {
+		setting = model.ConversationSetting{
+			UserID: userid,
+			Type:   key.Type,
+			ChatID: key.ChatID,
+		}
+	}
+
+	if setting.Archived, err = formBool(c, "archived", setting.Archived); err != nil {
+		return echo.ErrBadRequest
+	}
+	if setting.MarkedUnread, err = formBool(c, "unread", setting.MarkedUnread); err != nil {
+		return echo.ErrBadRequest
+	}
+
+	switch v := c.FormValue("muteduntil"); v {
+	case "":
+	case "none":
+		setting.MutedUntil = nil
+	case "forever":
+		setting.MutedUntil = &muteForever
+	default:
+		until, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "muteduntil must be an RFC3339 time, \"forever\" or \"none\"",
+			})
+		}
+		setting.MutedUntil = &until
+	}
+
+	pinned, err := formBool(c, "pinned", setting.Pinned())
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	if setting.Archived {
+		pinned = false
+	}
+
+	if pinned && !setting.Pinned() {
+		count, last := 0, 0
+		for _, other := range all {
+			if other.Pinned() {
+				count++
+				if other.PinOrder > last {
+					last = other.PinOrder
+				}
+			}
+		}
+
+		if count >= model.MaxPinnedConversations {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"msg": "pinned conversation limit reached",
+			})
+		}
+		setting.PinOrder = last + 1
+	}
+	if !pinned {
+		setting.PinOrder = 0
+	}
+
+	if err = cs.repo.Save(c.Request().Context(), setting); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	cs.hub.Publish(userid, EventConversationSettings, setting)
+
+	return c.JSON(http.StatusOK, setting)
+}
+
+func (cs *ConversationSettings) ReorderPins(c echo.Context) error {
+	id := c.Get("userID")
+	userid, _ := id.(uint64)
+
+	all, err := loadSettings(c, cs.repo, userid)
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	if err = cs.unpinStale(c, userid, all); err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	var order []folders.Key
+	seen := make(map[folders.Key]bool)
+	for _, item := range strings.Split(c.FormValue("order"), ",") {
+		key, err := parseConversationKey(item)
+		if err != nil {
+			return echo.ErrBadRequest
+		}
+		if !all[key].Pinned() {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "order must list pinned conversations only",
+			})
+		}
+		if seen[key] {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"msg": "order must list every pinned conversation exactly once",
+			})
+		}
+		seen[key] = true
+		order = append(order, key)
+	}
+
+	pinned := 0
+	for _, setting := range all {
+		if setting.Pinned() {
+			pinned++
+		}
+	}
+	if len(order) != pinned {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"msg": "order must list every pinned conversation exactly once",
+		})
+	}
+
+	for position, key := range order {
+		setting := all[key]
+		setting.PinOrder = position + 1
+		if err = cs.repo.Save(c.Request().Context(), setting); err != nil {
+			return echo.ErrInternalServerError
+		}
+		cs.hub.Publish(userid, EventConversationSettings, setting)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"msg": "pins reordered",
+	})
+}
+
+func (cs *ConversationSettings) NewConversationSettingsHandler(g *echo.Group) {
+	settingsGroup := g.Group("/conversations")
+
+	settingsGroup.GET("/settings", cs.GetSettings, mwares.JWTMiddleware)
+	settingsGroup.PUT("/pins", cs.ReorderPins, mwares.JWTMiddleware)
+	settingsGroup.PATCH("/:type/:chatid/settings", cs.UpdateSettings, mwares.JWTMiddleware)
+}
@@ -2,11 +2,15 @@ package endpoints
 
 import (
 	"backend/internal/authorize"
+	"backend/internal/blocking"
 	"backend/internal/configs"
+	"backend/internal/events"
+	"backend/internal/membership"
 	"backend/internal/model"
 	"backend/internal/mwares"
 	"backend/utils"
 	"backend/utils/datasource"
+	"errors"
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
 	"net/http"
@@ -17,11 +21,18 @@ import (
 type User struct {
 	repo        model.UserRepository
 	contactRepo model.ContactRepository
+	membership  *membership.Service
+	blocks      *blocking.Service
+	hub         *events.Hub
 }
 
-func NewUser(repo model.UserRepository, contactRepo model.ContactRepository) *User {
+func NewUser(repo model.UserRepository, contactRepo model.ContactRepository, membership *membership.Service,
+	blocks *blocking.Service, hub *events.Hub) *User {
 	return &User{
 		contactRepo: contactRepo,
+		membership:  membership,
+		blocks:      blocks,
+		hub:         hub,
 		repo:        repo,
 	}
 }
@@ -104,6 +115,24 @@ func (u *User) LoginUser(c echo.Context) error {
 	})
 }
 
+// hideProfile blanks the picture and online status of user if they blocked
+// the caller.
+func (u *User) hideProfile(c echo.Context, user *model.User) error {
+	id := c.Get("userID")
+	viewerid, _ := id.(uint64)
+
+	err := u.blocks.Check(c.Request().Context(), viewerid, user.UserID)
+	if errors.Is(err, blocking.ErrBlocked) {
+		blocking.HideProfile(&user.ProfilePicture, &user.IsActive, &user.LastSeen)
+		return nil
+	}
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+
+	return nil
+}
+
 func (u *User) GetUserByID(c echo.Context) error {
 	id, err := strconv.ParseUint(c.Param("userid"), 10, 64)
 	if err != nil {
@@ -127,6 +156,10 @@ func (u *User) GetUserByID(c echo.Context) error {
 		return echo.ErrInternalServerError
 	}
 
+	if err = u.hideProfile(c, &users[0]); err != nil {
+		return err
+	}
+
 	return c.JSON(http.StatusOK, users[0])
 }
 
@@ -219,6 +252,16 @@ func (u *User) GetUserPfpf(c echo.Context) error {
 		return echo.ErrInternalServerError
 	}
 
+	if len(users) == 0 {
+		return echo.ErrNotFound
+	}
+
+	id := c.Get("userID")
+	viewerid, _ := id.(uint64)
+	if err = checkBlocked(c, u.blocks, viewerid, users[0].UserID); err != nil {
+		return err
+	}
+
 	pfp := users[0].ProfilePicture
 
 	conf, err := configs.LoadConfig()
@@ -241,150 +284,45 @@ func (u *User) GetUserPfpf(c echo.Context) error {
 }
 
 func (u *User) DeleteUser(c echo.Context) error {
-	username := c.Param("username")
-
-	if err := u.repo.Delete(c.Request().Context(), model.UserInterface{
-		Username: &username,
-	}); err != nil {
-		return echo.ErrInternalServerError
-	}
-
-	return c.JSON(http.StatusOK, map[string]string{
-		"msg": "user deleted",
-	})
-}
-
-func (u *User) GetUserByKey(c echo.Context) error {
-	keyword := c.QueryParam("keyword")
-
-	users, err := u.repo.Get(c.Request().Context(), model.UserInterface{
-		Username: &keyword,
-	})
+	user, err := u.self(c)
 	if err != nil {
-		return echo.ErrInternalServerError
+		return err
 	}
 
-	return c.JSON(http.StatusOK, users[0])
-}
-
-func (u *User) GetUserContacts(c echo.Context) error {
-	username := c.Param("username")
-
-	users, err := u.repo.Get(c.Request().Context(), model.UserInterface{
-		Username: &username,
-	})
-	if err != nil {
-		return echo.ErrInternalServerError
-	}
-
-	contacts, err := u.contactRepo.Get(c.Request().Context(), model.ContactInterface{
-		UserID: &users[0].UserID,
-	})
-	if err != nil {
+	// Groups the user owns pass to a successor rather than being orphaned.
+	if err = u.membership.LeaveAll(c.Request().Context(), user.UserID); err != nil {
 		return echo.ErrInternalServerError
 	}
 
-	return c.JSON(http.StatusOK, contacts)
-}
-
-func (u *User) NewUserContact(c echo.Context) error {
-	username := c.Param("username")
-
-	contactUsername := c.FormValue("username")
-
-	users, err := u.repo.Get(c.Request().Context(), model.UserInterface{
-		Username: &username,
-	})
-	if err != nil {
-		return echo.ErrInternalServerError
-	}
-	contactUsers, err := u.repo.Get(c.Request().Context(), model.UserInterface{
-		Username: &contactUsername,
-	})
-	if err != nil {
-		return echo.ErrInternalServerError
-	}
-
-	oldContact, err := u.contactRepo.Get(c.Request().Context(), model.ContactInterface{
-		UserID:        &users[0].UserID,
-		ContactUserID: &contactUsers[0].UserID,
-	})
-	if err != nil {
-		return echo.ErrInternalServerError
-	}
-
-	if len(oldContact) != 0 {
-		return c.JSON(http.StatusConflict, map[string]string{
-			"msg": "contact already exist",
-		})
-	}
-
-	if err := u.contactRepo.Create(c.Request().Context(), model.Contact{
-		UserID:        users[0].UserID,
-		ContactUserID: contactUsers[0].UserID,
-		Status:        model.Pending,
-	}); err != nil {
-		return echo.ErrInternalServerError
-	}
-
-	return c.JSON(http.StatusCreated, map[string]string{
-		"msg": "contact created",
-	})
-}
-
-func (u *User) DeleteUserContact(c echo.Context) error {
-	username := c.Param("username")
-
-	id, err := strconv.ParseUint(c.Param("contactid"), 10, 64)
-	if err != nil {
-		return echo.ErrBadRequest
-	}
-
-	users, err := u.repo.Get(c.Request().Context(), model.UserInterface{
-		Username: &username,
-	})
-	if err != nil {
-		return echo.ErrInternalServerError
-	}
-
-	if err = u.contactRepo.Delete(c.Request().Context(), model.ContactInterface{
-		UserID:        &users[0].UserID,
-		ContactUserID: &id,
+	if err = u.repo.Delete(c.Request().Context(), model.UserInterface{
+		ID: &user.UserID,
 	}); err != nil {
 		return echo.ErrInternalServerError
 	}
 
 	return c.JSON(http.StatusOK, map[string]string{
-		"msg": "deleted contact successfuly",
+		"msg": "user deleted",
 	})
 }
 
-func (u *User) UpdateContact(c echo.Context) error {
-	username := c.Param("username")
-
-	id, err := strconv.ParseUint(c.Param("contactid"), 10, 64)
-	if err != nil {
-		return echo.ErrBadRequest
-	}
+func (u *User) GetUserByKey(c echo.Context) error {
+	keyword := c.QueryParam("keyword")
 
 	users, err := u.repo.Get(c.Request().Context(), model.UserInterface{
-		Username: &username,
+		Username: &keyword,
 	})
 	if err != nil {
 		return echo.ErrInternalServerError
 	}
+	if len(users) == 0 {
+		return echo.ErrNotFound
+	}
 
-	if err := u.contactRepo.Update(c.Request().Context(), model.Contact{
-		UserID:        users[0].UserID,
-		ContactUserID: id,
-		Status:        model.Accepted,
-	}); err != nil {
-		return echo.ErrInternalServerError
+	if err = u.hideProfile(c, &users[0]); err != nil {
+		return err
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"msg": "contact status updated",
-	})
+	return c.JSON(http.StatusOK, users[0])
 }
 
 func (u *User) UpdateOnlineStatus(c echo.Context) error {
@@ -416,6 +354,14 @@ func (u *User) NewUserHandler(g *echo.Group) {
 
 	userGroup.GET("/:username/contacts", u.GetUserContacts, mwares.JWTMiddleware)
 	userGroup.POST("/:username/contacts", u.NewUserContact, mwares.JWTMiddleware)
+	userGroup.GET("/:username/contacts/requests", u.GetContactRequests, mwares.JWTMiddleware)
 	userGroup.DELETE("/:username/contacts/:contactid", u.DeleteUserContact, mwares.JWTMiddleware)
 	userGroup.PATCH("/:username/contacts/:contactid", u.UpdateContact, mwares.JWTMiddleware)
+	userGroup.POST("/:username/contacts/:contactid/accept", u.UpdateContact, mwares.JWTMiddleware)
+	userGroup.POST("/:username/contacts/:contactid/decline", u.DeclineContact, mwares.JWTMiddleware)
+
+	blocksGroup := g.Group("/blocks")
+	blocksGroup.GET("", u.GetBlocks, mwares.JWTMiddleware)
+	blocksGroup.PUT("/:userid", u.BlockUser, mwares.JWTMiddleware)
+	blocksGroup.DELETE("/:userid", u.UnblockUser, mwares.JWTMiddleware)
 }
@@ -2,11 +2,30 @@ package api
 
 import (
 	"backend/api/endpoints"
+	"backend/internal/blocking"
 	"backend/internal/configs"
+	"backend/internal/events"
+	"backend/internal/export"
+	"backend/internal/flood"
+	"backend/internal/folders"
+	"backend/internal/importer"
+	"backend/internal/membership"
+	"backend/internal/repositoryImpl/bookmarkRepoImpl"
+	"backend/internal/repositoryImpl/channelRepoImpl"
+	"backend/internal/repositoryImpl/channelViewRepoImpl"
+	"backend/internal/repositoryImpl/communityRepoImpl"
 	"backend/internal/repositoryImpl/contactRepoImpl"
-	"backend/internal/repositoryImpl/groupChatRepoImpl"
+	"backend/internal/repositoryImpl/folderRepoImpl"
+	"backend/internal/repositoryImpl/groupBanRepoImpl"
+	"backend/internal/repositoryImpl/groupInviteRepoImpl"
+	"backend/internal/repositoryImpl/groupProfileChangeRepoImpl"
 	"backend/internal/repositoryImpl/groupRepoImpl"
+	"backend/internal/repositoryImpl/groupTopicRepoImpl"
+	"backend/internal/repositoryImpl/inboxRepoImpl"
+	"backend/internal/repositoryImpl/joinRequestRepoImpl"
 	"backend/internal/repositoryImpl/messageRepoImpl"
+	"backend/internal/repositoryImpl/settingRepoImpl"
+	"backend/internal/repositoryImpl/userChannelRepoImpl"
 	"backend/internal/repositoryImpl/userChatRepoImpl"
 	"backend/internal/repositoryImpl/userGroupRepoImpl"
 	"backend/internal/repositoryImpl/userRepoImpl"
@@ -16,24 +35,48 @@ import (
 )
 
 type repos struct {
-	userRepo      *userRepoImpl.Repository
-	messageRepo   *messageRepoImpl.Repository
-	groupRepo     *groupRepoImpl.Repository
-	contactRepo   *contactRepoImpl.Repository
-	userGroupRepo *userGroupRepoImpl.Repository
-	groupChatRepo *groupChatRepoImpl.Repository
-	userChatRepo  *userChatRepoImpl.Repository
+	userRepo          *userRepoImpl.Repository
+	messageRepo       *messageRepoImpl.Repository
+	groupRepo         *groupRepoImpl.Repository
+	contactRepo       *contactRepoImpl.Repository
+	userGroupRepo     *userGroupRepoImpl.Repository
+	userChatRepo      *userChatRepoImpl.Repository
+	bookmarkRepo      *bookmarkRepoImpl.Repository
+	channelRepo       *channelRepoImpl.Repository
+	userChannelRepo   *userChannelRepoImpl.Repository
+	channelViewRepo   *channelViewRepoImpl.Repository
+	folderRepo        *folderRepoImpl.Repository
+	settingRepo       *settingRepoImpl.Repository
+	inboxRepo         *inboxRepoImpl.Repository
+	inviteRepo        *groupInviteRepoImpl.Repository
+	joinRequestRepo   *joinRequestRepoImpl.Repository
+	banRepo           *groupBanRepoImpl.Repository
+	profileChangeRepo *groupProfileChangeRepoImpl.Repository
+	topicRepo         *groupTopicRepoImpl.Repository
+	communityRepo     *communityRepoImpl.Repository
 }
 
 func initRepos(db *gorm.DB) *repos {
 	return &repos{
-		userRepo:      userRepoImpl.New(db),
-		messageRepo:   messageRepoImpl.New(db),
-		groupRepo:     groupRepoImpl.New(db),
-		contactRepo:   contactRepoImpl.New(db),
-		userGroupRepo: userGroupRepoImpl.New(db),
-		groupChatRepo: groupChatRepoImpl.New(db),
-		userChatRepo:  userChatRepoImpl.New(db),
+		userRepo:          userRepoImpl.New(db),
+		messageRepo:       messageRepoImpl.New(db),
+		groupRepo:         groupRepoImpl.New(db),
+		contactRepo:       contactRepoImpl.New(db),
+		userGroupRepo:     userGroupRepoImpl.New(db),
+		userChatRepo:      userChatRepoImpl.New(db),
+		bookmarkRepo:      bookmarkRepoImpl.New(db),
+		channelRepo:       channelRepoImpl.New(db),
+		userChannelRepo:   userChannelRepoImpl.New(db),
+		channelViewRepo:   channelViewRepoImpl.New(db),
+		folderRepo:        folderRepoImpl.New(db),
+		settingRepo:       settingRepoImpl.New(db),
+		inboxRepo:         inboxRepoImpl.New(db),
+		inviteRepo:        groupInviteRepoImpl.New(db),
+		joinRequestRepo:   joinRequestRepoImpl.New(db),
+		banRepo:           groupBanRepoImpl.New(db),
+		profileChangeRepo: groupProfileChangeRepoImpl.New(db),
+		topicRepo:         groupTopicRepoImpl.New(db),
+		communityRepo:     communityRepoImpl.New(db),
 	}
 }
 
@@ -42,15 +85,47 @@ func Run(db *gorm.DB, conf *configs.Config) {
 
 	e := echo.New()
 
-	hu := endpoints.NewUser(repos.userRepo, repos.contactRepo)
-	hc := endpoints.NewUserChat(repos.userChatRepo, repos.messageRepo)
-	hg := endpoints.NewGroup(repos.groupRepo, repos.messageRepo, repos.userGroupRepo, repos.groupChatRepo)
+	hub := events.NewHub()
+	folderEvaluator := folders.New(repos.folderRepo, repos.settingRepo, repos.userChatRepo, repos.userGroupRepo, repos.contactRepo,
+		repos.messageRepo)
+
+	blocks := blocking.New(repos.contactRepo)
+	limiter := flood.New(flood.DefaultRate, flood.DefaultRatePeriod, flood.DefaultDuplicateWindow)
+	groupMembership := membership.New(repos.groupRepo, repos.userGroupRepo, repos.userRepo, repos.messageRepo,
+		repos.banRepo, hub)
+
+	hu := endpoints.NewUser(repos.userRepo, repos.contactRepo, groupMembership, blocks, hub)
+	hc := endpoints.NewUserChat(repos.userChatRepo, repos.userRepo, repos.messageRepo, repos.settingRepo, folderEvaluator, limiter, blocks, hub)
+	hg := endpoints.NewGroup(repos.groupRepo, repos.messageRepo, repos.userGroupRepo, repos.inviteRepo, repos.joinRequestRepo,
+		repos.profileChangeRepo, repos.topicRepo, repos.settingRepo, folderEvaluator, groupMembership, limiter, blocks, hub)
+	hs := endpoints.NewSearch(repos.messageRepo)
+	hsv := endpoints.NewSaved(repos.bookmarkRepo, repos.messageRepo, repos.userChatRepo, repos.userGroupRepo, repos.userChannelRepo)
+	hch := endpoints.NewChannel(repos.channelRepo, repos.messageRepo, repos.userChannelRepo, repos.channelViewRepo)
+	hex := endpoints.NewExport(export.New(repos.messageRepo, repos.userRepo, repos.userChatRepo, repos.groupRepo, repos.channelRepo),
+		repos.userChatRepo, repos.userGroupRepo, repos.userChannelRepo)
+	him := endpoints.NewImport(importer.New(db))
+	hf := endpoints.NewFolder(repos.folderRepo, folderEvaluator)
+	hcs := endpoints.NewConversationSettings(repos.settingRepo, repos.userChatRepo, repos.userGroupRepo, repos.userChannelRepo, hub)
+	hev := endpoints.NewEvents(hub)
+	hin := endpoints.NewInbox(repos.inboxRepo, blocks)
+	hco := endpoints.NewCommunity(repos.communityRepo, repos.groupRepo, repos.userGroupRepo, repos.channelRepo,
+		repos.userChannelRepo, groupMembership, hg)
 
 	apiGroup := e.Group("/api")
 
 	hu.NewUserHandler(apiGroup)
 	hc.NewUserChatHandler(apiGroup)
 	hg.NewGroupHandler(apiGroup)
+	hs.NewSearchHandler(apiGroup)
+	hsv.NewSavedHandler(apiGroup)
+	hch.NewChannelHandler(apiGroup)
+	hex.NewExportHandler(apiGroup)
+	him.NewImportHandler(apiGroup)
+	hf.NewFolderHandler(apiGroup)
+	hcs.NewConversationSettingsHandler(apiGroup)
+	hev.NewEventsHandler(apiGroup)
+	hin.NewInboxHandler(apiGroup)
+	hco.NewCommunityHandler(apiGroup)
 
 	if err := e.Start(conf.Server.Address + ":" + conf.Server.Port); err != nil {
 		logrus.Fatalf("server failed to start %v", err)
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"backend/internal/configs"
+	"backend/internal/export"
+	"backend/internal/model"
+	"backend/internal/repositoryImpl/channelRepoImpl"
+	"backend/internal/repositoryImpl/groupRepoImpl"
+	"backend/internal/repositoryImpl/messageRepoImpl"
+	"backend/internal/repositoryImpl/userChatRepoImpl"
+	"backend/internal/repositoryImpl/userRepoImpl"
+	"backend/utils/datasource"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func parseTime(v string) *time.Time {
+	if v == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		logrus.Fatalf("invalid time %q, expected RFC3339: %v", v, err)
+	}
+
+	return &t
+}
+
+// export writes a conversation archive to a zip file. It talks to the
+// database directly and is meant for operators, so it skips the per-user
+// authorization the HTTP endpoint applies.
+func main() {
+	chatType := flag.String("type", "", "conversation type: pv, gp or ch")
+	chatID := flag.Uint64("id", 0, "chat, group or channel id")
+	from := flag.String("from", "", "only export messages sent at or after this RFC3339 time")
+	to := flag.String("to", "", "only export messages sent before this RFC3339 time")
+	out := flag.String("out", "", "output zip file (defaults to <type>-<id>.zip)")
+	flag.Parse()
+
+	t := model.MessageType(strings.ToUpper(*chatType))
+	if (t != model.TypePV && t != model.TypeGP && t != model.TypeCH) || *chatID == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *out == "" {
+		*out = fmt.Sprintf("%s-%d.zip", strings.ToLower(string(t)), *chatID)
+	}
+
+	conf, err := configs.LoadConfig()
+	if err != nil {
+		logrus.Warnln("error in loading configs")
+	}
+
+	db, err := datasource.ConnectPostgres(conf.Database)
+	if err != nil {
+		logrus.Fatalf("failed to connect database %v", err)
+	}
+
+	exporter := export.New(messageRepoImpl.New(db), userRepoImpl.New(db), userChatRepoImpl.New(db), groupRepoImpl.New(db),
+		channelRepoImpl.New(db))
+
+	archive, err := exporter.Build(context.Background(), t, *chatID, parseTime(*from), parseTime(*to))
+	if err != nil {
+		logrus.Fatalf("failed to build archive %v", err)
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		logrus.Fatalf("failed to create %s: %v", *out, err)
+	}
+	defer file.Close()
+
+	if err = exporter.WriteZip(context.Background(), file, archive); err != nil {
+		logrus.Fatalf("failed to write archive %v", err)
+	}
+
+	logrus.Infof("exported %d messages to %s", archive.Count(), *out)
+}
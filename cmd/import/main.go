@@ -0,0 +1,67 @@
+package main
+
+import (
+	"backend/internal/configs"
+	"backend/internal/importer"
+	"backend/utils/datasource"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// import loads a Telegram Desktop result.json export into the database on
+// behalf of an existing user and prints the import report as JSON.
+func main() {
+	file := flag.String("file", "result.json", "telegram export to import")
+	userID := flag.Uint64("user", 0, "id of the importing user")
+	mappingFile := flag.String("mapping", "", "JSON file mapping telegram from_id to username or phone")
+	dryRun := flag.Bool("dry-run", false, "only report what would be imported")
+	flag.Parse()
+
+	if *userID == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	mapping := make(map[string]string)
+	if *mappingFile != "" {
+		data, err := os.ReadFile(*mappingFile)
+		if err != nil {
+			logrus.Fatalf("failed to read mapping %v", err)
+		}
+		if err = json.Unmarshal(data, &mapping); err != nil {
+			logrus.Fatalf("invalid mapping %v", err)
+		}
+	}
+
+	in, err := os.Open(*file)
+	if err != nil {
+		logrus.Fatalf("failed to open %s: %v", *file, err)
+	}
+	defer in.Close()
+
+	conf, err := configs.LoadConfig()
+	if err != nil {
+		logrus.Warnln("error in loading configs")
+	}
+
+	db, err := datasource.ConnectPostgres(conf.Database)
+	if err != nil {
+		logrus.Fatalf("failed to connect database %v", err)
+	}
+
+	imp := importer.New(db)
+
+	report, err := imp.Telegram(context.Background(), *userID, in, mapping, *dryRun)
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	}
+	if err != nil {
+		logrus.Fatalf("import failed %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package blocking
+
+import (
+	"backend/internal/model"
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrBlocked = errors.New("this user has blocked you")
+
+// Service is the one place that decides whether a user may reach another.
+// A block is a contact row of the blocker with status Blocked; while it
+// exists the blocked user can not open a chat with, message, add to a
+// group, send a contact request to, or see the picture and presence of the
+// blocker.
+type Service struct {
+	contactRepo model.ContactRepository
+}
+
+func New(contactRepo model.ContactRepository) *Service {
+	return &Service{
+		contactRepo: contactRepo,
+	}
+}
+
+// HideProfile clears the picture and presence of a user shown to someone
+// they have blocked, whichever view of the user they appear in.
+func HideProfile(profilePicture, isActive *string, lastSeen *time.Time) {
+	*profilePicture = ""
+	*isActive = "false"
+	*lastSeen = time.Time{}
+}
+
+// Check returns ErrBlocked when targetID has blocked actorID.
+func (s *Service) Check(ctx context.Context, actorID, targetID uint64) error {
+	if actorID == targetID {
+		return nil
+	}
+
+	blocked := model.Blocked
+	blocks, err := s.contactRepo.Get(ctx, model.ContactInterface{
+		UserID:        &targetID,
+		ContactUserID: &actorID,
+		Status:        &blocked,
+	})
+	if err != nil {
+		return err
+	}
+	if len(blocks) != 0 {
+		return ErrBlocked
+	}
+
+	return nil
+}
+
+// BlockedBy returns the users who have blocked userID, for hiding their
+// presence from lists.
+func (s *Service) BlockedBy(ctx context.Context, userID uint64) (map[uint64]bool, error) {
+	blocked := model.Blocked
+	blocks, err := s.contactRepo.Get(ctx, model.ContactInterface{
+		ContactUserID: &userID,
+		Status:        &blocked,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	blockers := make(map[uint64]bool, len(blocks))
+	for _, block := range blocks {
+		blockers[block.UserID] = true
+	}
+
+	return blockers, nil
+}
+
+// Block makes blockerID block userID. The blocker's own contact row becomes
+// the block, and the other user's contact or pending request is dropped.
+func (s *Service) Block(ctx context.Context, blockerID, userID uint64) error {
+	contacts, err := s.contactRepo.Get(ctx, model.ContactInterface{
+		UserID:        &blockerID,
+		ContactUserID: &userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(contacts) == 0 {
+		err = s.contactRepo.Create(ctx, model.Contact{
+			UserID:        blockerID,
+			ContactUserID: userID,
+			Status:        model.Blocked,
+		})
+	} else {
+		contacts[0].Status = model.Blocked
+		err = s.contactRepo.Update(ctx, contacts[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, status := range []model.Status{model.Pending, model.Accepted} {
+		if err = s.contactRepo.Delete(ctx, model.ContactInterface{
+			UserID:        &userID,
+			ContactUserID: &blockerID,
+			Status:        &status,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Unblock lifts blockerID's block on userID, reporting whether there was
+// one.
+func (s *Service) Unblock(ctx context.Context, blockerID, userID uint64) (bool, error) {
+	if err := s.Check(ctx, userID, blockerID); !errors.Is(err, ErrBlocked) {
+		return false, err
+	}
+
+	blocked := model.Blocked
+	return true, s.contactRepo.Delete(ctx, model.ContactInterface{
+		UserID:        &blockerID,
+		ContactUserID: &userID,
+		Status:        &blocked,
+	})
+}
+
+// List returns the users blockerID has blocked.
+func (s *Service) List(ctx context.Context, blockerID uint64) ([]model.Contact, error) {
+	blocked := model.Blocked
+	return s.contactRepo.Get(ctx, model.ContactInterface{
+		UserID: &blockerID,
+		Status: &blocked,
+	})
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+type Event struct {
+	Type string    `json:"type"`
+	Data any       `json:"data"`
+	At   time.Time `json:"at"`
+}
+
+// Hub fans events out to every connection a user currently has open. It is
+// in-memory, so delivery is best-effort and limited to this process.
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[uint64]map[chan Event]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subs: make(map[uint64]map[chan Event]struct{}),
+	}
+}
+
+func (h *Hub) Subscribe(userID uint64) chan Event {
+	ch := make(chan Event, 32)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[chan Event]struct{})
+	}
+	h.subs[userID][ch] = struct{}{}
+
+	return ch
+}
+
+func (h *Hub) Unsubscribe(userID uint64, ch chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[userID][ch]; !ok {
+		return
+	}
+	delete(h.subs[userID], ch)
+	if len(h.subs[userID]) == 0 {
+		delete(h.subs, userID)
+	}
+	close(ch)
+}
+
+// Publish sends an event to all of the user's connections. Slow connections
+// whose buffer is full miss the event rather than block the publisher.
+func (h *Hub) Publish(userID uint64, eventType string, data any) {
+	event := Event{
+		Type: eventType,
+		Data: data,
+		At:   time.Now(),
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subs[userID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (h *Hub) PublishMany(userIDs []uint64, eventType string, data any) {
+	for _, userID := range userIDs {
+		h.Publish(userID, eventType, data)
+	}
+}
@@ -0,0 +1,363 @@
+package export
+
+import (
+	"archive/zip"
+	"backend/internal/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var ErrConversationNotFound = errors.New("conversation not found")
+
+type Conversation struct {
+	Type  model.MessageType `json:"type"`
+	ID    uint64            `json:"id"`
+	Title string            `json:"title"`
+}
+
+type Sender struct {
+	UserID   uint64 `json:"userID"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+}
+
+type Attachment struct {
+	Kind     model.ContentKind `json:"kind"`
+	URL      string            `json:"url"`
+	Name     string            `json:"name,omitempty"`
+	MimeType string            `json:"mimeType,omitempty"`
+}
+
+type Message struct {
+	MessageID  uint64            `json:"messageID"`
+	SenderID   uint64            `json:"senderID"`
+	SenderName string            `json:"senderName"`
+	Kind       model.ContentKind `json:"kind"`
+	Content    string            `json:"content"`
+	Payload    json.RawMessage   `json:"payload,omitempty"`
+	Attachment *Attachment       `json:"attachment,omitempty"`
+	SentAt     time.Time         `json:"sentAt"`
+	UpdatedAt  time.Time         `json:"updatedAt"`
+}
+
+// exportPage is how many messages are read from the database at a time.
+const exportPage = 500
+
+// Archive describes an export. Its messages are read page by page while the
+// zip is written, so it never holds the whole conversation in memory.
+type Archive struct {
+	Conversation Conversation `json:"conversation"`
+	ExportedAt   time.Time    `json:"exportedAt"`
+	From         *time.Time   `json:"from,omitempty"`
+	To           *time.Time   `json:"to,omitempty"`
+	Senders      []Sender     `json:"senders"`
+
+	senders map[uint64]Sender
+	count   int
+}
+
+type Exporter struct {
+	messageRepo model.MessageRepository
+	userRepo    model.UserRepository
+	chatRepo    model.UserChatRepository
+	groupRepo   model.GroupRepository
+	channelRepo model.ChannelRepository
+}
+
+func New(messageRepo model.MessageRepository, userRepo model.UserRepository, chatRepo model.UserChatRepository,
+	groupRepo model.GroupRepository, channelRepo model.ChannelRepository) *Exporter {
+	return &Exporter{
+		messageRepo: messageRepo,
+		channelRepo: channelRepo,
+		groupRepo:   groupRepo,
+		chatRepo:    chatRepo,
+		userRepo:    userRepo,
+	}
+}
+
+func (e *Exporter) title(ctx context.Context, chatType model.MessageType, chatID uint64) (string, error) {
+	switch chatType {
+	case model.TypePV:
+		chats, err := e.chatRepo.Get(ctx, model.ChatInterface{
+			ID: &chatID,
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(chats) == 0 {
+			return "", ErrConversationNotFound
+		}
+
+		names := make([]string, 0, 2)
+		for _, userID := range []uint64{chats[0].UserID, chats[0].ReceiverID} {
+			users, err := e.userRepo.Get(ctx, model.UserInterface{
+				ID: &userID,
+			})
+			if err != nil {
+				return "", err
+			}
+			if len(users) != 0 {
+				names = append(names, users[0].Name)
+			}
+		}
+
+		if len(names) == 2 {
+			return names[0] + " & " + names[1], nil
+		}
+		return fmt.Sprintf("Chat %d", chatID), nil
+	case model.TypeGP:
+		groups, err := e.groupRepo.Get(ctx, model.GroupInterface{
+			ID: &chatID,
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(groups) == 0 {
+			return "", ErrConversationNotFound
+		}
+
+		return groups[0].Name, nil
+	case model.TypeCH:
+		channels, err := e.channelRepo.Get(ctx, model.ChannelInterface{
+			ID: &chatID,
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(channels) == 0 {
+			return "", ErrConversationNotFound
+		}
+
+		return channels[0].Name, nil
+	}
+
+	return "", ErrConversationNotFound
+}
+
+func attachmentOf(message model.MessageDTO) *Attachment {
+	switch message.Kind {
+	case model.KindImage, model.KindFile, model.KindVoice:
+	default:
+		return nil
+	}
+
+	var ref struct {
+		URL      string `json:"url"`
+		Name     string `json:"name"`
+		MimeType string `json:"mimeType"`
+	}
+	if err := json.Unmarshal(message.Payload, &ref); err != nil || ref.URL == "" {
+		return nil
+	}
+
+	return &Attachment{
+		Kind:     message.Kind,
+		URL:      ref.URL,
+		Name:     ref.Name,
+		MimeType: ref.MimeType,
+	}
+}
+
+// Count is the number of messages written to messages.json, which is
+// exported before index.html.
+func (a *Archive) Count() int {
+	return a.count
+}
+
+// Build resolves the conversation to export and the range of messages it
+// covers. Callers are responsible for authorization.
+func (e *Exporter) Build(ctx context.Context, chatType model.MessageType, chatID uint64, from, to *time.Time) (*Archive, error) {
+	title, err := e.title(ctx, chatType, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Archive{
+		Conversation: Conversation{
+			Type:  chatType,
+			ID:    chatID,
+			Title: title,
+		},
+		ExportedAt: time.Now(),
+		From:       from,
+		To:         to,
+		Senders:    []Sender{},
+		senders:    make(map[uint64]Sender),
+	}, nil
+}
+
+// loadSenders looks up, in one query, the senders of page that the archive
+// has not seen yet.
+func (e *Exporter) loadSenders(ctx context.Context, archive *Archive, page []model.MessageDTO) error {
+	var missing []uint64
+	for _, message := range page {
+		if _, ok := archive.senders[message.SenderID]; !ok {
+			archive.senders[message.SenderID] = Sender{
+				UserID: message.SenderID,
+				Name:   "Deleted account",
+			}
+			missing = append(missing, message.SenderID)
+		}
+	}
+
+	users, err := e.userRepo.GetMany(ctx, missing)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		archive.senders[user.UserID] = Sender{
+			UserID:   user.UserID,
+			Name:     user.Name,
+			Username: user.Username,
+		}
+	}
+	for _, senderID := range missing {
+		archive.Senders = append(archive.Senders, archive.senders[senderID])
+	}
+
+	return nil
+}
+
+// each calls fn with the archive's messages in order, oldest first.
+func (e *Exporter) each(ctx context.Context, archive *Archive, fn func(Message) error) error {
+	var after *uint64
+	for {
+		page, err := e.messageRepo.GetRange(ctx, model.MessageInterface{
+			ChatID: &archive.Conversation.ID,
+			Type:   &archive.Conversation.Type,
+		}, archive.From, archive.To, after, exportPage)
+		if err != nil {
+			return err
+		}
+
+		if err = e.loadSenders(ctx, archive, page); err != nil {
+			return err
+		}
+
+		for _, message := range page {
+			err = fn(Message{
+				MessageID:  message.MessageID,
+				SenderID:   message.SenderID,
+				SenderName: archive.senders[message.SenderID].Name,
+				Kind:       message.Kind,
+				Content:    message.Content,
+				Payload:    message.Payload,
+				Attachment: attachmentOf(message),
+				SentAt:     message.CreatedAt,
+				UpdatedAt:  message.UpdatedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(page) < exportPage {
+			return nil
+		}
+		last := page[len(page)-1].MessageID
+		after = &last
+	}
+}
+
+// writeJSON writes messages.json one message at a time, with the senders
+// seen along the way after them.
+func (e *Exporter) writeJSON(ctx context.Context, w io.Writer, archive *Archive) error {
+	head, err := json.MarshalIndent(struct {
+		Conversation Conversation `json:"conversation"`
+		ExportedAt   time.Time    `json:"exportedAt"`
+		From         *time.Time   `json:"from,omitempty"`
+		To           *time.Time   `json:"to,omitempty"`
+	}{archive.Conversation, archive.ExportedAt, archive.From, archive.To}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Reopen the object to append the messages and senders to it.
+	if _, err = w.Write(head[:len(head)-2]); err != nil {
+		return err
+	}
+	if _, err = io.WriteString(w, ",\n  \"messages\": ["); err != nil {
+		return err
+	}
+
+	archive.count = 0
+	err = e.each(ctx, archive, func(message Message) error {
+		sep := ",\n    "
+		if archive.count == 0 {
+			sep = "\n    "
+		}
+		archive.count++
+
+		b, err := json.MarshalIndent(message, "    ", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(w, sep); err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	senders, err := json.MarshalIndent(archive.Senders, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(w, "\n  ],\n  \"senders\": "); err != nil {
+		return err
+	}
+	if _, err = w.Write(senders); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n}\n")
+	return err
+}
+
+// writeHTML renders index.html message by message.
+func (e *Exporter) writeHTML(ctx context.Context, w io.Writer, archive *Archive) error {
+	if err := archiveTemplate.ExecuteTemplate(w, "head", archive); err != nil {
+		return err
+	}
+
+	err := e.each(ctx, archive, func(message Message) error {
+		return archiveTemplate.ExecuteTemplate(w, "message", message)
+	})
+	if err != nil {
+		return err
+	}
+
+	return archiveTemplate.ExecuteTemplate(w, "foot", archive)
+}
+
+// WriteZip streams the archive to w as a zip holding messages.json and a
+// self-contained index.html rendering of the same data.
+func (e *Exporter) WriteZip(ctx context.Context, w io.Writer, archive *Archive) error {
+	zw := zip.NewWriter(w)
+
+	jw, err := zw.Create("messages.json")
+	if err != nil {
+		return err
+	}
+
+	if err = e.writeJSON(ctx, jw, archive); err != nil {
+		return err
+	}
+
+	hw, err := zw.Create("index.html")
+	if err != nil {
+		return err
+	}
+
+	if err = e.writeHTML(ctx, hw, archive); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,42 @@
+package export
+
+import "html/template"
+
+var archiveTemplate = template.Must(template.New("archive").Funcs(template.FuncMap{
+	"stamp": func(t interface{ Format(string) string }) string {
+		return t.Format("2006-01-02 15:04:05 MST")
+	},
+}).Parse(`{{define "head"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Conversation.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; background: #f4f4f5; margin: 0; }
+header { background: #2b5278; color: #fff; padding: 16px 24px; }
+header h1 { margin: 0; font-size: 20px; }
+header p { margin: 4px 0 0; font-size: 13px; opacity: .8; }
+main { max-width: 760px; margin: 0 auto; padding: 16px; }
+.message { background: #fff; border-radius: 8px; padding: 10px 14px; margin: 8px 0; box-shadow: 0 1px 2px rgba(0,0,0,.08); }
+.meta { font-size: 12px; color: #71717a; margin-bottom: 4px; }
+.sender { font-weight: 600; color: #2b5278; }
+.content { white-space: pre-wrap; word-wrap: break-word; }
+.kind { font-size: 12px; color: #a1a1aa; }
+.attachment { font-size: 13px; margin-top: 4px; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Conversation.Title}}</h1>
+<p>{{.Count}} messages &middot; exported {{stamp .ExportedAt}}{{if .From}} &middot; from {{stamp .From}}{{end}}{{if .To}} &middot; to {{stamp .To}}{{end}}</p>
+</header>
+<main>
+{{end}}{{define "message"}}<div class="message" id="message-{{.MessageID}}">
+<div class="meta"><span class="sender">{{.SenderName}}</span> &middot; {{stamp .SentAt}}{{if ne .Kind "text"}} <span class="kind">[{{.Kind}}]</span>{{end}}</div>
+{{if .Content}}<div class="content">{{.Content}}</div>{{end}}
+{{with .Attachment}}<div class="attachment">{{if .Name}}{{.Name}}{{else}}{{.Kind}}{{end}}: <a href="{{.URL}}">{{.URL}}</a></div>{{end}}
+</div>
+{{end}}{{define "foot"}}</main>
+</body>
+</html>
+{{end}}`))
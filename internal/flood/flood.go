@@ -0,0 +1,222 @@
+package flood
+
+import (
+	"backend/internal/model"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxSlowMode is the longest interval a group can impose between
+	// messages of the same member.
+	MaxSlowMode = time.Hour
+
+	DefaultRate            = 30
+	DefaultRatePeriod      = time.Minute
+	DefaultDuplicateWindow = 30 * time.Second
+)
+
+// Reasons a message is refused.
+const (
+	ReasonSlowMode    = "slow_mode"
+	ReasonRateLimited = "rate_limited"
+	ReasonDuplicate   = "duplicate"
+)
+
+// Error is returned when a message is refused. RetryAfter is how long the
+// sender has to wait before the same message would be accepted.
+type Error struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	switch e.Reason {
+	case ReasonSlowMode:
+		return "slow mode is on in this group"
+	case ReasonDuplicate:
+		return "you already sent this message"
+	}
+
+	return "you are sending messages too fast"
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds, the unit of the
+// Retry-After header.
+func (e *Error) RetryAfterSeconds() int {
+	seconds := int(e.RetryAfter / time.Second)
+	if e.RetryAfter%time.Second != 0 {
+		seconds++
+	}
+
+	return seconds
+}
+
+// Conversation is where a message is being sent.
+type Conversation struct {
+	Type   model.MessageType
+	ChatID uint64
+}
+
+type slowKey struct {
+	Conversation
+	UserID uint64
+}
+
+type sent struct {
+	conv Conversation
+	hash [sha256.Size]byte
+	at   time.Time
+	// prevLast is what last held for the sender in conv before this
+	// message, so Undo can put it back.
+	prevLast time.Time
+}
+
+// Limiter decides whether a user may send a message right now: the group's
+// slow mode, a per-user rate across every conversation, and a refusal to
+// repeat the same message in the same conversation within a short window.
+// Like the event hub it keeps its state in memory, so limits apply per
+// process.
+type Limiter struct {
+	rate            int
+	ratePeriod      time.Duration
+	duplicateWindow time.Duration
+
+	mu     sync.Mutex
+	recent map[uint64][]sent
+	last   map[slowKey]time.Time
+	checks int
+}
+
+func New(rate int, ratePeriod, duplicateWindow time.Duration) *Limiter {
+	return &Limiter{
+		duplicateWindow: duplicateWindow,
+		ratePeriod:      ratePeriod,
+		rate:            rate,
+		recent:          make(map[uint64][]sent),
+		last:            make(map[slowKey]time.Time),
+	}
+}
+
+func fingerprint(kind model.ContentKind, content string, payload json.RawMessage) [sha256.Size]byte {
+	return sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s", kind, content, payload)))
+}
+
+// Allow records a message from userID to conv and returns nil, or refuses
+// it without recording it. A slowMode of zero means the sender is
+// not subject to slow mode. A message that is allowed but then fails to be
+// stored is handed back with Undo.
+func (l *Limiter) Allow(userID uint64, conv Conversation, slowMode time.Duration, kind model.ContentKind, content string,
+	payload json.RawMessage) *Error {
+	now := time.Now()
+	hash := fingerprint(kind, content, payload)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.checks++
+	if l.checks%1024 == 0 {
+		l.sweep(now)
+	}
+
+	key := slowKey{Conversation: conv, UserID: userID}
+	if last, ok := l.last[key]; ok && slowMode > 0 && now.Sub(last) < slowMode {
+		return &Error{
+			Reason:     ReasonSlowMode,
+			RetryAfter: last.Add(slowMode).Sub(now),
+		}
+	}
+
+	window := l.ratePeriod
+	if l.duplicateWindow > window {
+		window = l.duplicateWindow
+	}
+
+	recent := l.recent[userID][:0]
+	for _, s := range l.recent[userID] {
+		if now.Sub(s.at) < window {
+			recent = append(recent, s)
+		}
+	}
+	l.recent[userID] = recent
+
+	inPeriod := 0
+	for _, s := range recent {
+		if s.conv == conv && s.hash == hash && now.Sub(s.at) < l.duplicateWindow {
+			return &Error{
+				Reason:     ReasonDuplicate,
+				RetryAfter: s.at.Add(l.duplicateWindow).Sub(now),
+			}
+		}
+		if now.Sub(s.at) < l.ratePeriod {
+			inPeriod++
+		}
+	}
+
+	if inPeriod >= l.rate {
+		// The oldest message still in the period is the next to leave it.
+		for _, s := range recent {
+			if now.Sub(s.at) < l.ratePeriod {
+				return &Error{
+					Reason:     ReasonRateLimited,
+					RetryAfter: s.at.Add(l.ratePeriod).Sub(now),
+				}
+			}
+		}
+	}
+
+	l.recent[userID] = append(recent, sent{conv: conv, hash: hash, at: now, prevLast: l.last[key]})
+	l.last[key] = now
+
+	return nil
+}
+
+// Undo forgets the latest message Allow recorded from userID to conv with
+// this content, so a message that was never stored counts neither against
+// the rate nor the slow mode, nor as a duplicate of its retry.
+func (l *Limiter) Undo(userID uint64, conv Conversation, kind model.ContentKind, content string, payload json.RawMessage) {
+	hash := fingerprint(kind, content, payload)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recent := l.recent[userID]
+	for i := len(recent) - 1; i >= 0; i-- {
+		s := recent[i]
+		if s.conv != conv || s.hash != hash {
+			continue
+		}
+
+		l.recent[userID] = append(recent[:i], recent[i+1:]...)
+
+		// Only rewind the slow mode if nothing was sent there since.
+		key := slowKey{Conversation: conv, UserID: userID}
+		if l.last[key].Equal(s.at) {
+			if s.prevLast.IsZero() {
+				delete(l.last, key)
+			} else {
+				l.last[key] = s.prevLast
+			}
+		}
+
+		return
+	}
+}
+
+// sweep drops state that can no longer refuse a message.
+func (l *Limiter) sweep(now time.Time) {
+	for key, last := range l.last {
+		if now.Sub(last) >= MaxSlowMode {
+			delete(l.last, key)
+		}
+	}
+	for userID, recent := range l.recent {
+		if len(recent) == 0 || (now.Sub(recent[len(recent)-1].at) >= l.ratePeriod &&
+			now.Sub(recent[len(recent)-1].at) >= l.duplicateWindow) {
+			delete(l.recent, userID)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package flood
+
+import (
+	"backend/internal/model"
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	group := Conversation{Type: model.TypeGP, ChatID: 1}
+	chat := Conversation{Type: model.TypePV, ChatID: 2}
+
+	type send struct {
+		user     uint64
+		conv     Conversation
+		slowMode time.Duration
+		content  string
+		reason   string
+	}
+
+	tests := []struct {
+		name  string
+		rate  int
+		sends []send
+	}{
+		{
+			name: "different messages",
+			rate: 10,
+			sends: []send{
+				{user: 1, conv: chat, content: "a"},
+				{user: 1, conv: chat, content: "b"},
+			},
+		},
+		{
+			name: "duplicate in the same conversation",
+			rate: 10,
+			sends: []send{
+				{user: 1, conv: chat, content: "a"},
+				{user: 1, conv: chat, content: "a", reason: ReasonDuplicate},
+			},
+		},
+		{
+			name: "same message elsewhere",
+			rate: 10,
+			sends: []send{
+				{user: 1, conv: chat, content: "a"},
+				{user: 1, conv: group, content: "a"},
+				{user: 2, conv: chat, content: "a"},
+			},
+		},
+		{
+			name: "rate across conversations",
+			rate: 2,
+			sends: []send{
+				{user: 1, conv: chat, content: "a"},
+				{user: 1, conv: group, content: "b"},
+				{user: 1, conv: chat, content: "c", reason: ReasonRateLimited},
+				{user: 2, conv: chat, content: "c"},
+			},
+		},
+		{
+			name: "slow mode",
+			rate: 10,
+			sends: []send{
+				{user: 1, conv: group, slowMode: time.Minute, content: "a"},
+				{user: 1, conv: group, slowMode: time.Minute, content: "b", reason: ReasonSlowMode},
+				{user: 1, conv: group, content: "c"},
+				{user: 2, conv: group, slowMode: time.Minute, content: "d"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(tt.rate, time.Minute, time.Minute)
+
+			for i, s := range tt.sends {
+				err := l.Allow(s.user, s.conv, s.slowMode, model.KindText, s.content, nil)
+				if s.reason == "" {
+					if err != nil {
+						t.Fatalf("send %d refused: %v", i, err)
+					}
+					continue
+				}
+				if err == nil {
+					t.Fatalf("send %d allowed, want %s", i, s.reason)
+				}
+				if err.Reason != s.reason {
+					t.Fatalf("send %d refused with %s, want %s", i, err.Reason, s.reason)
+				}
+				if err.RetryAfter <= 0 || err.RetryAfter > time.Minute {
+					t.Fatalf("send %d RetryAfter = %v", i, err.RetryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterUndo(t *testing.T) {
+	group := Conversation{Type: model.TypeGP, ChatID: 1}
+	l := New(1, time.Minute, time.Minute)
+
+	if err := l.Allow(1, group, time.Minute, model.KindText, "a", nil); err != nil {
+		t.Fatalf("first send refused: %v", err)
+	}
+	l.Undo(1, group, model.KindText, "a", nil)
+
+	// The retry is neither a duplicate, over the rate nor within slow mode.
+	if err := l.Allow(1, group, time.Minute, model.KindText, "a", nil); err != nil {
+		t.Fatalf("retry refused: %v", err)
+	}
+
+	// Undoing a message that was never allowed changes nothing.
+	l.Undo(1, group, model.KindText, "b", nil)
+	if err := l.Allow(1, group, 0, model.KindText, "b", nil); err == nil || err.Reason != ReasonRateLimited {
+		t.Fatalf("got %v, want %s", err, ReasonRateLimited)
+	}
+}
+
+func TestErrorRetryAfterSeconds(t *testing.T) {
+	tests := []struct {
+		retryAfter time.Duration
+		want       int
+	}{
+		{retryAfter: 0, want: 0},
+		{retryAfter: time.Second, want: 1},
+		{retryAfter: 1500 * time.Millisecond, want: 2},
+		{retryAfter: time.Millisecond, want: 1},
+	}
+
+	for _, tt := range tests {
+		err := &Error{Reason: ReasonRateLimited, RetryAfter: tt.retryAfter}
+		if got := err.RetryAfterSeconds(); got != tt.want {
+			t.Errorf("RetryAfterSeconds(%v) = %d, want %d", tt.retryAfter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package folders
+
+import (
+	"backend/internal/model"
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrFolderNotFound = errors.New("folder not found")
+
+type Key struct {
+	Type   model.MessageType
+	ChatID uint64
+}
+
+type Conversation struct {
+	Key
+	Unread       int64
+	IsContact    bool
+	Muted        bool
+	Archived     bool
+	MarkedUnread bool
+}
+
+// Badge is the unread count shown for the conversation; a conversation
+// marked as unread counts as at least one.
+func (c Conversation) Badge() int64 {
+	if c.MarkedUnread && c.Unread == 0 {
+		return 1
+	}
+
+	return c.Unread
+}
+
+// Matches reports whether conv belongs in folder. Explicit excludes always
+// win. The base set is the explicit includes plus every conversation of a
+// selected category (groups, contacts); a folder with neither starts from
+// all conversations. Archived conversations only appear when included
+// explicitly. The unread and muted rules then filter that set.
+func Matches(folder model.Folder, entries []model.FolderEntry, conv Conversation) bool {
+	included := false
+	hasIncludes := false
+	for _, entry := range entries {
+		if entry.Type != conv.Type || entry.ChatID != conv.ChatID {
+			if entry.Mode == model.FolderInclude {
+				hasIncludes = true
+			}
+			continue
+		}
+		if entry.Mode == model.FolderExclude {
+			return false
+		}
+		included = true
+		hasIncludes = true
+	}
+
+	if !included {
+		if conv.Archived {
+			return false
+		}
+
+		switch {
+		case folder.GroupsOnly || folder.ContactsOnly:
+			inGroups := folder.GroupsOnly && conv.Type == model.TypeGP
+			inContacts := folder.ContactsOnly && conv.Type == model.TypePV && conv.IsContact
+			if !inGroups && !inContacts {
+				return false
+			}
+		case hasIncludes:
+			return false
+		}
+	}
+
+	if folder.UnreadOnly && conv.Badge() == 0 {
+		return false
+	}
+	if folder.ExcludeMuted && conv.Muted {
+		return false
+	}
+
+	return true
+}
+
+type Evaluator struct {
+	repo          model.FolderRepository
+	settingRepo   model.ConversationSettingRepository
+	chatRepo      model.UserChatRepository
+	userGroupRepo model.UserGroupRepository
+	contactRepo   model.ContactRepository
+	messageRepo   model.MessageRepository
+}
+
+func New(repo model.FolderRepository, settingRepo model.ConversationSettingRepository, chatRepo model.UserChatRepository,
+	userGroupRepo model.UserGroupRepository, contactRepo model.ContactRepository, messageRepo model.MessageRepository) *Evaluator {
+	return &Evaluator{
+		settingRepo:   settingRepo,
+		userGroupRepo: userGroupRepo,
+		contactRepo:   contactRepo,
+		messageRepo:   messageRepo,
+		chatRepo:      chatRepo,
+		repo:          repo,
+	}
+}
+
+// Conversations lists every private chat and group of userID with the
+// attributes folder rules look at.
+func (e *Evaluator) Conversations(ctx context.Context, userID uint64) ([]Conversation, error) {
+	chats, err := e.chatRepo.Get(ctx, model.ChatInterface{
+		UserID: &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	receiverChats, err := e.chatRepo.Get(ctx, model.ChatInterface{
+		ReceiverID: &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	chats = append(chats, receiverChats...)
+
+	groups, err := e.userGroupRepo.Get(ctx, model.UserGroupInterface{
+		UserID: &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := model.Accepted
+	contacts, err := e.contactRepo.Get(ctx, model.ContactInterface{
+		UserID: &userID,
+		Status: &accepted,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	isContact := make(map[uint64]bool, len(contacts))
+	for _, contact := range contacts {
+		isContact[contact.ContactUserID] = true
+	}
+
+	chatIDs := make([]uint64, len(chats))
+	for i, chat := range chats {
+		chatIDs[i] = chat.ChatID
+	}
+	groupIDs := make([]uint64, len(groups))
+	for i, group := range groups {
+		groupIDs[i] = group.GroupID
+	}
+
+	chatUnread, err := e.messageRepo.CountUnread(ctx, userID, model.TypePV, chatIDs)
+	if err != nil {
+		return nil, err
+	}
+	groupUnread, err := e.messageRepo.CountUnread(ctx, userID, model.TypeGP, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := e.settingRepo.Get(ctx, model.ConversationSettingInterface{
+		UserID: &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	settingOf := make(map[Key]model.ConversationSetting, len(settings))
+	for _, setting := range settings {
+		settingOf[Key{Type: setting.Type, ChatID: setting.ChatID}] = setting
+	}
+
+	now := time.Now()
+	convs := make([]Conversation, 0, len(chats)+len(groups))
+	for _, chat := range chats {
+		other := chat.ReceiverID
+		if other == userID {
+			other = chat.UserID
+		}
+
+		key := Key{Type: model.TypePV, ChatID: chat.ChatID}
+		convs = append(convs, Conversation{
+			Key:          key,
+			Unread:       chatUnread[chat.ChatID],
+			IsContact:    isContact[other],
+			Muted:        settingOf[key].Muted(now),
+			Archived:     settingOf[key].Archived,
+			MarkedUnread: settingOf[key].MarkedUnread,
+		})
+	}
+	for _, group := range groups {
+		key := Key{Type: model.TypeGP, ChatID: group.GroupID}
+		convs = append(convs, Conversation{
+			Key:          key,
+			Unread:       groupUnread[group.GroupID],
+			Muted:        settingOf[key].Muted(now),
+			Archived:     settingOf[key].Archived,
+			MarkedUnread: settingOf[key].MarkedUnread,
+		})
+	}
+
+	return convs, nil
+}
+
+// Filter returns the conversations of userID that fall in folderID.
+func (e *Evaluator) Filter(ctx context.Context, userID, folderID uint64) (map[Key]bool, error) {
+	folders, err := e.repo.Get(ctx, model.FolderInterface{
+		ID:     &folderID,
+		UserID: &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(folders) == 0 {
+		return nil, ErrFolderNotFound
+	}
+
+	entries, err := e.repo.GetEntries(ctx, []uint64{folderID})
+	if err != nil {
+		return nil, err
+	}
+
+	convs, err := e.Conversations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make(map[Key]bool)
+	for _, conv := range convs {
+		if Matches(folders[0], entries, conv) {
+			matched[conv.Key] = true
+		}
+	}
+
+	return matched, nil
+}
+
+// UnreadTotals sums the unread badges of the conversations in each folder,
+// leaving muted conversations out.
+func (e *Evaluator) UnreadTotals(ctx context.Context, userID uint64, folders []model.Folder,
+	entries map[uint64][]model.FolderEntry) (map[uint64]int64, error) {
+	convs, err := e.Conversations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[uint64]int64, len(folders))
+	for _, folder := range folders {
+		for _, conv := range convs {
+			if !conv.Muted && Matches(folder, entries[folder.FolderID], conv) {
+				totals[folder.FolderID] += conv.Badge()
+			}
+		}
+	}
+
+	return totals, nil
+}
@@ -0,0 +1,152 @@
+package folders
+
+import (
+	"backend/internal/model"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	group := Key{Type: model.TypeGP, ChatID: 1}
+	chat := Key{Type: model.TypePV, ChatID: 2}
+	other := Key{Type: model.TypePV, ChatID: 3}
+
+	include := func(k Key) model.FolderEntry {
+		return model.FolderEntry{Type: k.Type, ChatID: k.ChatID, Mode: model.FolderInclude}
+	}
+	exclude := func(k Key) model.FolderEntry {
+		return model.FolderEntry{Type: k.Type, ChatID: k.ChatID, Mode: model.FolderExclude}
+	}
+
+	tests := []struct {
+		name    string
+		folder  model.Folder
+		entries []model.FolderEntry
+		conv    Conversation
+		want    bool
+	}{
+		{
+			name: "no rules matches everything",
+			conv: Conversation{Key: chat},
+			want: true,
+		},
+		{
+			name: "no rules hides archived",
+			conv: Conversation{Key: chat, Archived: true},
+		},
+		{
+			name:    "explicit include",
+			entries: []model.FolderEntry{include(chat)},
+			conv:    Conversation{Key: chat},
+			want:    true,
+		},
+		{
+			name:    "explicit include shows archived",
+			entries: []model.FolderEntry{include(chat)},
+			conv:    Conversation{Key: chat, Archived: true},
+			want:    true,
+		},
+		{
+			name:    "includes leave out the rest",
+			entries: []model.FolderEntry{include(chat)},
+			conv:    Conversation{Key: other},
+		},
+		{
+			name:    "exclude",
+			entries: []model.FolderEntry{exclude(chat)},
+			conv:    Conversation{Key: chat},
+		},
+		{
+			name:    "exclude beats include",
+			entries: []model.FolderEntry{include(chat), exclude(chat)},
+			conv:    Conversation{Key: chat},
+		},
+		{
+			name:   "groups only takes groups",
+			folder: model.Folder{GroupsOnly: true},
+			conv:   Conversation{Key: group},
+			want:   true,
+		},
+		{
+			name:   "groups only leaves out chats",
+			folder: model.Folder{GroupsOnly: true},
+			conv:   Conversation{Key: chat, IsContact: true},
+		},
+		{
+			name:    "groups only with an included chat",
+			folder:  model.Folder{GroupsOnly: true},
+			entries: []model.FolderEntry{include(chat)},
+			conv:    Conversation{Key: chat},
+			want:    true,
+		},
+		{
+			name:   "contacts only takes contacts",
+			folder: model.Folder{ContactsOnly: true},
+			conv:   Conversation{Key: chat, IsContact: true},
+			want:   true,
+		},
+		{
+			name:   "contacts only leaves out strangers",
+			folder: model.Folder{ContactsOnly: true},
+			conv:   Conversation{Key: chat},
+		},
+		{
+			name:   "contacts only leaves out groups",
+			folder: model.Folder{ContactsOnly: true},
+			conv:   Conversation{Key: group},
+		},
+		{
+			name:   "unread only hides read",
+			folder: model.Folder{UnreadOnly: true},
+			conv:   Conversation{Key: chat},
+		},
+		{
+			name:   "unread only takes unread",
+			folder: model.Folder{UnreadOnly: true},
+			conv:   Conversation{Key: chat, Unread: 2},
+			want:   true,
+		},
+		{
+			name:   "unread only takes marked unread",
+			folder: model.Folder{UnreadOnly: true},
+			conv:   Conversation{Key: chat, MarkedUnread: true},
+			want:   true,
+		},
+		{
+			name:    "unread only filters includes too",
+			folder:  model.Folder{UnreadOnly: true},
+			entries: []model.FolderEntry{include(chat)},
+			conv:    Conversation{Key: chat},
+		},
+		{
+			name:   "exclude muted",
+			folder: model.Folder{ExcludeMuted: true},
+			conv:   Conversation{Key: group, Muted: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(tt.folder, tt.entries, tt.conv); got != tt.want {
+				t.Fatalf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBadge(t *testing.T) {
+	tests := []struct {
+		conv Conversation
+		want int64
+	}{
+		{conv: Conversation{}, want: 0},
+		{conv: Conversation{Unread: 3}, want: 3},
+		{conv: Conversation{MarkedUnread: true}, want: 1},
+		{conv: Conversation{Unread: 3, MarkedUnread: true}, want: 3},
+	}
+
+	for _, tt := range tests {
+		if got := tt.conv.Badge(); got != tt.want {
+			t.Errorf("Badge(%+v) = %d, want %d", tt.conv, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+package importer
+
+import (
+	"backend/internal/model"
+	"backend/internal/repositoryImpl/groupRepoImpl"
+	"backend/internal/repositoryImpl/messageRepoImpl"
+	"backend/internal/repositoryImpl/userChatRepoImpl"
+	"backend/internal/repositoryImpl/userGroupRepoImpl"
+	"backend/internal/repositoryImpl/userRepoImpl"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidExport   = errors.New("invalid telegram export")
+	ErrUnsupportedChat = errors.New("unsupported telegram chat type")
+	ErrNotParticipant  = errors.New("both participants of a personal chat must be mapped, and one must be the importing user")
+)
+
+// TelegramText is the "text" field of an exported message, which Telegram
+// writes either as a plain string or as a list of strings and entity objects.
+type TelegramText string
+
+func (t *TelegramText) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = TelegramText(s)
+		return nil
+	}
+
+	var parts []json.RawMessage
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for _, part := range parts {
+		var s string
+		if err := json.Unmarshal(part, &s); err == nil {
+			b.WriteString(s)
+			continue
+		}
+
+		var entity struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(part, &entity); err != nil {
+			return err
+		}
+		b.WriteString(entity.Text)
+	}
+
+	*t = TelegramText(b.String())
+	return nil
+}
+
+type TelegramMessage struct {
+	ID        int64        `json:"id"`
+	Type      string       `json:"type"`
+	Date      string       `json:"date"`
+	DateUnix  string       `json:"date_unixtime"`
+	From      string       `json:"from"`
+	FromID    string       `json:"from_id"`
+	Text      TelegramText `json:"text"`
+	ReplyTo   int64        `json:"reply_to_message_id"`
+	Photo     string       `json:"photo"`
+	File      string       `json:"file"`
+	MediaType string       `json:"media_type"`
+}
+
+type TelegramExport struct {
+	ID       int64             `json:"id"`
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Messages []TelegramMessage `json:"messages"`
+}
+
+type UnmappedSender struct {
+	FromID   string `json:"fromID"`
+	Name     string `json:"name"`
+	Messages int    `json:"messages"`
+}
+
+type Report struct {
+	DryRun   bool              `json:"dryRun"`
+	Title    string            `json:"title"`
+	ChatType model.MessageType `json:"chatType"`
+	ChatID   uint64            `json:"chatID,omitempty"`
+	Members  map[string]uint64 `json:"members"`
+	Messages int               `json:"messages"`
+	Imported int               `json:"imported"`
+	Skipped  int               `json:"skipped"`
+	Unmapped []UnmappedSender  `json:"unmapped"`
+}
+
+type Importer struct {
+	db       *gorm.DB
+	userRepo model.UserRepository
+}
+
+func New(db *gorm.DB) *Importer {
+	return &Importer{
+		db:       db,
+		userRepo: userRepoImpl.New(db),
+	}
+}
+
+// store holds the repositories an import writes through, all bound to the
+// same transaction.
+type store struct {
+	chatRepo      model.UserChatRepository
+	groupRepo     model.GroupRepository
+	userGroupRepo model.UserGroupRepository
+	messageRepo   model.MessageRepository
+}
+
+func newStore(tx *gorm.DB) store {
+	return store{
+		chatRepo:      userChatRepoImpl.New(tx),
+		groupRepo:     groupRepoImpl.New(tx),
+		userGroupRepo: userGroupRepoImpl.New(tx),
+		messageRepo:   messageRepoImpl.New(tx),
+	}
+}
+
+// resolver picks the user a message is stored under; 0 is a sender that
+// can not be attributed, whose messages are skipped.
+type resolver func(ctx context.Context, message TelegramMessage) (uint64, error)
+
+// pending is a message that will be imported.
+type pending struct {
+	telegramID int64
+	message    model.MessageDTO
+}
+
+func (t TelegramMessage) sentAt() time.Time {
+	if t.DateUnix != "" {
+		if sec, err := strconv.ParseInt(t.DateUnix, 10, 64); err == nil {
+			return time.Unix(sec, 0)
+		}
+	}
+
+	if d, err := time.Parse("2006-01-02T15:04:05", t.Date); err == nil {
+		return d
+	}
+
+	return time.Now()
+}
+
+func (t TelegramMessage) content() string {
+	text := strings.TrimSpace(string(t.Text))
+	if text != "" {
+		return text
+	}
+
+	switch {
+	case t.Photo != "":
+		return "[photo]"
+	case t.MediaType != "":
+		return "[" + t.MediaType + "]"
+	case t.File != "":
+		return "[file]"
+	}
+
+	return ""
+}
+
+// resolve maps a Telegram sender to the user its mapping entry (keyed by
+// from_id) names, tried as a username and then as a phone number. Senders
+// without an entry stay unmapped.
+func (i *Importer) resolve(ctx context.Context, fromID string, mapping map[string]string) (uint64, error) {
+	key := strings.TrimPrefix(strings.TrimSpace(mapping[fromID]), "@")
+	if key == "" {
+		return 0, nil
+	}
+
+	users, err := i.userRepo.Get(ctx, model.UserInterface{
+		Username: &key,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(users) != 0 {
+		return users[0].UserID, nil
+	}
+
+	users, err = i.userRepo.Get(ctx, model.UserInterface{
+		Phone: &key,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(users) != 0 {
+		return users[0].UserID, nil
+	}
+
+	return 0, nil
+}
+
+func chatTypeOf(export TelegramExport) (model.MessageType, error) {
+	switch export.Type {
+	case "personal_chat", "bot_chat":
+		return model.TypePV, nil
+	case "private_group", "private_supergroup", "public_supergroup":
+		return model.TypeGP, nil
+	}
+
+	return "", fmt.Errorf("%w: %q", ErrUnsupportedChat, export.Type)
+}
+
+func decode(r io.Reader) (*TelegramExport, model.MessageType, error) {
+	var export TelegramExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+
+	chatType, err := chatTypeOf(export)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &export, chatType, nil
+}
+
+// plan walks the export, attributing each message with resolve, and
+// returns the messages the import would store. Messages without content
+// and those of unmapped senders are counted as skipped.
+func plan(ctx context.Context, export *TelegramExport, report *Report, resolve resolver) ([]pending, error) {
+	var messages []pending
+	unmapped := make(map[string]*UnmappedSender)
+	for _, message := range export.Messages {
+		if message.Type != "message" {
+			continue
+		}
+		report.Messages++
+
+		content := message.content()
+		if content == "" {
+			report.Skipped++
+			continue
+		}
+
+		userID, err := resolve(ctx, message)
+		if err != nil {
+			return nil, err
+		}
+		if userID == 0 {
+			if u, seen := unmapped[message.FromID]; seen {
+				u.Messages++
+			} else {
+				unmapped[message.FromID] = &UnmappedSender{
+					FromID:   message.FromID,
+					Name:     message.From,
+					Messages: 1,
+				}
+			}
+			report.Skipped++
+			continue
+		}
+
+		report.Members[message.FromID] = userID
+		messages = append(messages, pending{
+			telegramID: message.ID,
+			message: model.MessageDTO{
+				Message: model.Message{
+					SenderID: userID,
+					Kind:     model.KindText,
+					Content:  content,
+					IsRead:   "true",
+				},
+				CreatedAt: message.sentAt(),
+			},
+		})
+	}
+	report.Imported = len(messages)
+
+	for _, sender := range unmapped {
+		report.Unmapped = append(report.Unmapped, *sender)
+	}
+	sort.Slice(report.Unmapped, func(a, b int) bool {
+		return report.Unmapped[a].Messages > report.Unmapped[b].Messages
+	})
+
+	return messages, nil
+}
+
+func newReport(export *TelegramExport, chatType model.MessageType, dryRun bool) *Report {
+	return &Report{
+		DryRun:   dryRun,
+		Title:    export.Name,
+		ChatType: chatType,
+		Members:  make(map[string]uint64),
+		Unmapped: []UnmappedSender{},
+	}
+}
+
+// Telegram imports a single-chat Telegram Desktop "result.json" export on
+// behalf of importerID, storing each message under the user mapping names
+// for its sender, matched by username and then by phone. With dryRun set it
+// only resolves senders and reports what would be imported.
+func (i *Importer) Telegram(ctx context.Context, importerID uint64, r io.Reader, mapping map[string]string, dryRun bool) (*Report, error) {
+	export, chatType, err := decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	senders := make(map[string]uint64)
+	report := newReport(export, chatType, dryRun)
+	messages, err := plan(ctx, export, report, func(ctx context.Context, message TelegramMessage) (uint64, error) {
+		userID, ok := senders[message.FromID]
+		if !ok {
+			var err error
+			if userID, err = i.resolve(ctx, message.FromID, mapping); err != nil {
+				return 0, err
+			}
+			senders[message.FromID] = userID
+		}
+
+		return userID, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[uint64]bool)
+	for _, userID := range senders {
+		if userID != 0 {
+			members[userID] = true
+		}
+	}
+
+	if chatType == model.TypePV {
+		if !members[importerID] || len(members) != 2 {
+			return report, ErrNotParticipant
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	return report, i.write(ctx, importerID, chatType, export, members, messages, report)
+}
+
+// write creates the conversation and stores messages in one transaction,
+// so a failed import leaves nothing behind.
+func (i *Importer) write(ctx context.Context, importerID uint64, chatType model.MessageType, export *TelegramExport,
+	members map[uint64]bool, messages []pending, report *Report) error {
+	return i.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		s := newStore(tx)
+
+		chatID, err := s.createConversation(ctx, chatType, export.Name, importerID, members)
+		if err != nil {
+			return err
+		}
+		report.ChatID = chatID
+
+		batch := make([]model.MessageDTO, len(messages))
+		for n, message := range messages {
+			batch[n] = message.message
+			batch[n].ChatID = chatID
+			batch[n].Type = chatType
+		}
+
+		ids, err := s.messageRepo.CreateBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+		report.Imported = len(ids)
+
+		byTelegramID := make(map[int64]uint64, len(ids))
+		for n, id := range ids {
+			byTelegramID[messages[n].telegramID] = id
+		}
+
+		replies := make(map[uint64]uint64)
+		for _, message := range export.Messages {
+			if message.ReplyTo == 0 {
+				continue
+			}
+			id, ok := byTelegramID[message.ID]
+			parent, parentOK := byTelegramID[message.ReplyTo]
+			if ok && parentOK {
+				replies[id] = parent
+			}
+		}
+
+		return s.messageRepo.SetReplies(ctx, replies)
+	})
+}
+
+func (s store) createConversation(ctx context.Context, chatType model.MessageType, name string, importerID uint64,
+	members map[uint64]bool) (uint64, error) {
+	if chatType == model.TypeGP {
+		groupID, err := s.groupRepo.Create(ctx, model.Group{
+			Name:        name,
+			Description: "Imported from Telegram",
+			Creator:     importerID,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		members[importerID] = true
+		for userID := range members {
+			role := model.GroupMember
+			if userID == importerID {
+				role = model.GroupOwner
+			}
+
+			if err = s.userGroupRepo.Create(ctx, model.UserGroup{
+				GroupID: groupID,
+				UserID:  userID,
+				Role:    role,
+			}); err != nil {
+				return 0, err
+			}
+		}
+
+		return groupID, nil
+	}
+
+	receiverID := importerID
+	for userID := range members {
+		if userID != importerID {
+			receiverID = userID
+		}
+	}
+
+	chat, _, err := s.chatRepo.Open(ctx, importerID, receiverID)
+	if err != nil {
+		return 0, err
+	}
+
+	return chat.ChatID, nil
+}
@@ -0,0 +1,376 @@
+package membership
+
+import (
+	"backend/internal/events"
+	"backend/internal/model"
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	ErrNotMember       = errors.New("user is not a member of this group")
+	ErrSuccessorMember = errors.New("the new owner must be a member of this group")
+	ErrSelfTransfer    = errors.New("you already own this group")
+	ErrOwnerBan        = errors.New("the owner of a group can not be removed or banned")
+	ErrBanned          = errors.New("you are banned from this group")
+	ErrBannerRank      = errors.New("this ban was placed by someone whose role is above yours")
+)
+
+// Outcome describes what leaving did to the group besides removing the
+// member.
+type Outcome struct {
+	Successor    uint64 `json:"successor,omitempty"`
+	GroupDeleted bool   `json:"groupDeleted"`
+}
+
+// Service changes who belongs to and owns a group, posting a system message
+// in the group for each change.
+type Service struct {
+	groupRepo     model.GroupRepository
+	userGroupRepo model.UserGroupRepository
+	userRepo      model.UserRepository
+	messageRepo   model.MessageRepository
+	banRepo       model.GroupBanRepository
+	hub           *events.Hub
+}
+
+func New(groupRepo model.GroupRepository, userGroupRepo model.UserGroupRepository, userRepo model.UserRepository,
+	messageRepo model.MessageRepository, banRepo model.GroupBanRepository, hub *events.Hub) *Service {
+	return &Service{
+		hub:           hub,
+		banRepo:       banRepo,
+		userGroupRepo: userGroupRepo,
+		messageRepo:   messageRepo,
+		groupRepo:     groupRepo,
+		userRepo:      userRepo,
+	}
+}
+
+// members returns the group's memberships in join order.
+func (s *Service) members(ctx context.Context, groupID uint64) ([]model.UserGroup, error) {
+	members, err := s.userGroupRepo.Get(ctx, model.UserGroupInterface{
+		GroupID: &groupID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].UserGroupID < members[j].UserGroupID
+	})
+
+	return members, nil
+}
+
+// successor picks who inherits the group: the longest-standing admin, then
+// the longest-standing member, and only then a restricted member.
+func successor(candidates []model.UserGroup) model.UserGroup {
+	best := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if candidate.Outranks(best) {
+			best = candidate
+		}
+	}
+
+	return best
+}
+
+// handOver makes next the owner. The previous owner stays on as an admin
+// with every permission.
+func (s *Service) handOver(ctx context.Context, groupID uint64, owner, next model.UserGroup) error {
+	if err := s.userGroupRepo.TransferOwnership(ctx, model.UserGroup{
+		GroupID: groupID,
+		UserID:  owner.UserID,
+		Role:    model.GroupAdmin,
+		GroupPermissions: model.GroupPermissions{
+			CanAddMembers:     true,
+			CanRemoveMembers:  true,
+			CanDeleteMessages: true,
+			CanPinMessages:    true,
+			CanEditInfo:       true,
+			CanManageTopics:   true,
+		},
+	}, model.UserGroup{
+		GroupID: groupID,
+		UserID:  next.UserID,
+		Role:    model.GroupOwner,
+	}); err != nil {
+		return err
+	}
+
+	return s.Announce(ctx, groupID, model.SystemPayload{
+		Event:    model.SystemOwnershipTransferred,
+		ActorID:  owner.UserID,
+		TargetID: next.UserID,
+	})
+}
+
+// Transfer hands groupID from its owner ownerID to newOwnerID, who must
+// already be a member.
+func (s *Service) Transfer(ctx context.Context, groupID, ownerID, newOwnerID uint64) error {
+	if ownerID == newOwnerID {
+		return ErrSelfTransfer
+	}
+
+	members, err := s.members(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	var owner, next *model.UserGroup
+	for i := range members {
+		switch members[i].UserID {
+		case ownerID:
+			owner = &members[i]
+		case newOwnerID:
+			next = &members[i]
+		}
+	}
+	if owner == nil {
+		return ErrNotMember
+	}
+	if next == nil {
+		return ErrSuccessorMember
+	}
+
+	return s.handOver(ctx, groupID, *owner, *next)
+}
+
+// Leave removes userID from groupID. An owner leaving first hands the group
+// to a successor; the last member leaving deletes the group.
+func (s *Service) Leave(ctx context.Context, groupID, userID uint64) (*Outcome, error) {
+	members, err := s.members(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	var self *model.UserGroup
+	others := make([]model.UserGroup, 0, len(members))
+	for i := range members {
+		if members[i].UserID == userID {
+			self = &members[i]
+			continue
+		}
+		others = append(others, members[i])
+	}
+	if self == nil {
+		return nil, ErrNotMember
+	}
+
+	outcome := &Outcome{}
+	if len(others) == 0 {
+		if err = s.groupRepo.Delete(ctx, model.GroupInterface{
+			ID: &groupID,
+		}); err != nil {
+			return nil, err
+		}
+
+		outcome.GroupDeleted = true
+		return outcome, nil
+	}
+
+	if self.Role == model.GroupOwner {
+		next := successor(others)
+		if err = s.handOver(ctx, groupID, *self, next); err != nil {
+			return nil, err
+		}
+		outcome.Successor = next.UserID
+	}
+
+	if err = s.userGroupRepo.Delete(ctx, model.UserGroupInterface{
+		GroupID: &groupID,
+		UserID:  &userID,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err = s.Announce(ctx, groupID, model.SystemPayload{
+		Event:    model.SystemMemberLeft,
+		ActorID:  userID,
+		TargetID: userID,
+	}); err != nil {
+		return nil, err
+	}
+
+	return outcome, nil
+}
+
+// LeaveAll removes userID from every group they are in, as when they delete
+// their account, so owned groups pass to a successor instead of being lost.
+func (s *Service) LeaveAll(ctx context.Context, userID uint64) error {
+	memberships, err := s.userGroupRepo.Get(ctx, model.UserGroupInterface{
+		UserID: &userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, membership := range memberships {
+		if _, err = s.Leave(ctx, membership.GroupID, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// remove deletes targetID's membership and records why in the group.
+func (s *Service) remove(ctx context.Context, groupID, actorID, targetID uint64, event, reason string) error {
+	if err := s.userGroupRepo.Delete(ctx, model.UserGroupInterface{
+		GroupID: &groupID,
+		UserID:  &targetID,
+	}); err != nil {
+		return err
+	}
+
+	return s.Announce(ctx, groupID, model.SystemPayload{
+		Event:    event,
+		ActorID:  actorID,
+		TargetID: targetID,
+		Reason:   reason,
+	})
+}
+
+// Remove kicks targetID out of groupID. They may come back through any of
+// the usual ways in; use Ban to keep them out.
+func (s *Service) Remove(ctx context.Context, groupID, actorID, targetID uint64, reason string) error {
+	target, err := s.userGroupRepo.Get(ctx, model.UserGroupInterface{
+		GroupID: &groupID,
+		UserID:  &targetID,
+	})
+	if err != nil {
+		return err
+	}
+	if len(target) == 0 {
+		return ErrNotMember
+	}
+	if target[0].Role == model.GroupOwner {
+		return ErrOwnerBan
+	}
+
+	return s.remove(ctx, groupID, actorID, targetID, model.SystemMemberRemoved, reason)
+}
+
+// Ban records ban, replacing any earlier ban of the same user unless that
+// one was placed by someone who outranks the new banner, and removes the
+// user from the group if they are still in it.
+func (s *Service) Ban(ctx context.Context, ban model.GroupBan) error {
+	target, err := s.userGroupRepo.Get(ctx, model.UserGroupInterface{
+		GroupID: &ban.GroupID,
+		UserID:  &ban.UserID,
+	})
+	if err != nil {
+		return err
+	}
+	if len(target) != 0 && target[0].Role == model.GroupOwner {
+		return ErrOwnerBan
+	}
+
+	if _, err = s.checkBanner(ctx, ban.GroupID, ban.UserID, ban.BannedBy); err != nil {
+		return err
+	}
+
+	if err = s.banRepo.Save(ctx, ban); err != nil {
+		return err
+	}
+
+	if len(target) == 0 {
+		return nil
+	}
+
+	return s.remove(ctx, ban.GroupID, ban.BannedBy, ban.UserID, model.SystemMemberBanned, ban.Reason)
+}
+
+// checkBanner returns the ban on userID, if any, and ErrBannerRank when it
+// is still in effect and was placed by a member who outranks actorID.
+func (s *Service) checkBanner(ctx context.Context, groupID, userID, actorID uint64) (*model.GroupBan, error) {
+	bans, err := s.banRepo.Get(ctx, model.GroupBanInterface{
+		GroupID: &groupID,
+		UserID:  &userID,
+	})
+	if err != nil || len(bans) == 0 {
+		return nil, err
+	}
+
+	ban := bans[0].GroupBan
+	if !bans[0].Active(time.Now()) || ban.BannedBy == actorID {
+		return &ban, nil
+	}
+
+	banner, err := s.userGroupRepo.Get(ctx, model.UserGroupInterface{
+		GroupID: &groupID,
+		UserID:  &ban.BannedBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	actor, err := s.userGroupRepo.Get(ctx, model.UserGroupInterface{
+		GroupID: &groupID,
+		UserID:  &actorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(banner) != 0 && (len(actor) == 0 || banner[0].Outranks(actor[0])) {
+		return &ban, ErrBannerRank
+	}
+
+	return &ban, nil
+}
+
+// Unban lets actorID lift the ban on userID, reporting whether there was
+// one. A ban still in effect can only be lifted by someone whose role is at
+// least that of the member who placed it.
+func (s *Service) Unban(ctx context.Context, groupID, userID, actorID uint64) (bool, error) {
+	ban, err := s.checkBanner(ctx, groupID, userID, actorID)
+	if err != nil {
+		return false, err
+	}
+	if ban == nil {
+		return false, nil
+	}
+
+	return true, s.banRepo.Delete(ctx, model.GroupBanInterface{
+		GroupID: &groupID,
+		UserID:  &userID,
+	})
+}
+
+// Bans lists the bans of groupID that are still in effect.
+func (s *Service) Bans(ctx context.Context, groupID uint64) ([]model.GroupBanDTO, error) {
+	bans, err := s.banRepo.Get(ctx, model.GroupBanInterface{
+		GroupID: &groupID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := make([]model.GroupBanDTO, 0, len(bans))
+	for _, ban := range bans {
+		if ban.Active(now) {
+			active = append(active, ban)
+		}
+	}
+
+	return active, nil
+}
+
+// CheckBan is the single check every way into a group goes through. It
+// returns ErrBanned while userID has an active ban in groupID.
+func (s *Service) CheckBan(ctx context.Context, groupID, userID uint64) (*model.GroupBan, error) {
+	bans, err := s.banRepo.Get(ctx, model.GroupBanInterface{
+		GroupID: &groupID,
+		UserID:  &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(bans) == 0 || !bans[0].Active(time.Now()) {
+		return nil, nil
+	}
+
+	return &bans[0].GroupBan, ErrBanned
+}
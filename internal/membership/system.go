@@ -0,0 +1,203 @@
+package membership
+
+import (
+	"backend/internal/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// EventSystemMessage carries a newly posted system message to the members
+// of its group.
+const EventSystemMessage = "group.system_message"
+
+func (s *Service) displayName(ctx context.Context, userID uint64) string {
+	users, err := s.userRepo.Get(ctx, model.UserInterface{
+		ID: &userID,
+	})
+	if err != nil || len(users) == 0 {
+		return "a former member"
+	}
+	if users[0].Name != "" {
+		return users[0].Name
+	}
+
+	return users[0].Username
+}
+
+// render is the one place the text of a system message is produced, so the
+// timeline reads the same whether it was just posted or loaded later.
+func render(p model.SystemPayload, name func(uint64) string) string {
+	var text string
+	switch p.Event {
+	case model.SystemGroupCreated:
+		text = fmt.Sprintf("%s created the group", name(p.ActorID))
+		if p.New != "" {
+			text = fmt.Sprintf("%s created the group %q", name(p.ActorID), p.New)
+		}
+	case model.SystemMemberAdded:
+		text = fmt.Sprintf("%s added %s", name(p.ActorID), name(p.TargetID))
+	case model.SystemMemberJoined:
+		text = fmt.Sprintf("%s joined the group", name(p.TargetID))
+	case model.SystemMemberLeft:
+		text = fmt.Sprintf("%s left the group", name(p.ActorID))
+	case model.SystemMemberRemoved:
+		text = fmt.Sprintf("%s removed %s", name(p.ActorID), name(p.TargetID))
+	case model.SystemMemberBanned:
+		text = fmt.Sprintf("%s banned %s", name(p.ActorID), name(p.TargetID))
+	case model.SystemOwnershipTransferred:
+		text = fmt.Sprintf("%s is now the owner of the group", name(p.TargetID))
+	case model.SystemTitleChanged:
+		text = fmt.Sprintf("%s renamed the group to %q", name(p.ActorID), p.New)
+		if p.TopicID != 0 {
+			text = fmt.Sprintf("%s renamed the topic to %q", name(p.ActorID), p.New)
+		}
+	case model.SystemDescriptionChanged:
+		text = fmt.Sprintf("%s changed the group description", name(p.ActorID))
+	case model.SystemAvatarChanged:
+		text = fmt.Sprintf("%s changed the group photo", name(p.ActorID))
+		if p.New == "" {
+			text = fmt.Sprintf("%s removed the group photo", name(p.ActorID))
+		}
+	case model.SystemMessagePinned:
+		text = fmt.Sprintf("%s pinned a message", name(p.ActorID))
+	case model.SystemTopicCreated:
+		text = fmt.Sprintf("%s created the topic %q", name(p.ActorID), p.New)
+	case model.SystemTopicClosed:
+		text = fmt.Sprintf("%s closed the topic", name(p.ActorID))
+	case model.SystemTopicReopened:
+		text = fmt.Sprintf("%s reopened the topic", name(p.ActorID))
+	case model.SystemWelcome:
+		// The welcome is filled in from the group's template when posted.
+		return p.New
+	default:
+		text = fmt.Sprintf("%s updated the group", name(p.ActorID))
+	}
+
+	if p.Reason != "" {
+		text += ": " + p.Reason
+	}
+
+	return text
+}
+
+// Announce posts a system message describing p in groupID and delivers it
+// live to everyone in the group, and to the target if the change removed
+// them.
+func (s *Service) Announce(ctx context.Context, groupID uint64, p model.SystemPayload) error {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	message := model.Message{
+		ChatID:   groupID,
+		TopicID:  p.TopicID,
+		SenderID: p.ActorID,
+		Type:     model.TypeGP,
+		Kind:     model.KindSystem,
+		Content:  render(p, func(userID uint64) string { return s.displayName(ctx, userID) }),
+		Payload:  payload,
+		IsRead:   "false",
+	}
+
+	messageID, err := s.messageRepo.Create(ctx, message)
+	if err != nil {
+		return err
+	}
+
+	posted, err := s.messageRepo.GetDto(ctx, model.MessageInterface{
+		ID: &messageID,
+	})
+	if err != nil {
+		return err
+	}
+	if len(posted) == 0 {
+		return nil
+	}
+
+	members, err := s.userGroupRepo.Get(ctx, model.UserGroupInterface{
+		GroupID: &groupID,
+	})
+	if err != nil {
+		return err
+	}
+
+	recipients := make([]uint64, 0, len(members)+1)
+	targetIncluded := p.TargetID == 0
+	for _, member := range members {
+		recipients = append(recipients, member.UserID)
+		if member.UserID == p.TargetID {
+			targetIncluded = true
+		}
+	}
+	if !targetIncluded {
+		recipients = append(recipients, p.TargetID)
+	}
+
+	s.hub.PublishMany(recipients, EventSystemMessage, posted[0])
+	return nil
+}
+
+// welcomeText fills the placeholders of a group's welcome template.
+func welcomeText(template, member, group string) string {
+	return strings.NewReplacer("{name}", member, "{group}", group).Replace(template)
+}
+
+// Joined records that userID came into groupID, added by actorID or on
+// their own, and posts the group's welcome message if it has one. Every way
+// into a group ends here.
+func (s *Service) Joined(ctx context.Context, groupID, actorID, userID uint64) error {
+	event := model.SystemMemberJoined
+	if actorID != userID {
+		event = model.SystemMemberAdded
+	}
+
+	if err := s.Announce(ctx, groupID, model.SystemPayload{
+		Event:    event,
+		ActorID:  actorID,
+		TargetID: userID,
+	}); err != nil {
+		return err
+	}
+
+	groups, err := s.groupRepo.Get(ctx, model.GroupInterface{
+		ID: &groupID,
+	})
+	if err != nil || len(groups) == 0 || groups[0].WelcomeMessage == "" {
+		return err
+	}
+
+	return s.Announce(ctx, groupID, model.SystemPayload{
+		Event:    model.SystemWelcome,
+		ActorID:  groups[0].Creator,
+		TargetID: userID,
+		New:      welcomeText(groups[0].WelcomeMessage, s.displayName(ctx, userID), groups[0].Name),
+	})
+}
+
+// Render rewrites the content of the system messages among messages from
+// their payloads, so they show members' current names.
+func (s *Service) Render(ctx context.Context, messages []model.MessageDTO) {
+	names := make(map[uint64]string)
+	name := func(userID uint64) string {
+		if n, ok := names[userID]; ok {
+			return n
+		}
+		names[userID] = s.displayName(ctx, userID)
+		return names[userID]
+	}
+
+	for i := range messages {
+		if messages[i].Kind != model.KindSystem {
+			continue
+		}
+
+		var p model.SystemPayload
+		if err := json.Unmarshal(messages[i].Payload, &p); err != nil || p.Event == "" {
+			continue
+		}
+		messages[i].Content = render(p, name)
+	}
+}
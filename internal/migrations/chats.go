@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// mergeDuplicateChats folds private chats opened from both sides (A→B and
+// B→A) into the oldest chat of the pair, moving messages, bookmarks, settings
+// and folder entries across, and then enforces one chat per unordered pair.
+func mergeDuplicateChats(tx *gorm.DB) error {
+	statements := []string{
+		`CREATE TEMP TABLE chat_merge ON COMMIT DROP AS
+		SELECT chat_id, keep_id FROM (
+			SELECT chat_id, MIN(chat_id) OVER (
+				PARTITION BY LEAST(user_id, receiver_id), GREATEST(user_id, receiver_id)
+			) AS keep_id
+			FROM user_chat_dtos
+		) pairs
+		WHERE chat_id <> keep_id`,
+
+		`UPDATE bookmark_dtos b SET chat_id = cm.keep_id
+		FROM chat_merge cm
+		WHERE b.type = 'PV' AND b.chat_id = cm.chat_id`,
+
+		// A user may have settings on several chats of the same pair; keep the
+		// ones on the lowest chat id, which is the surviving chat if present.
+		`DELETE FROM conversation_setting_dtos s
+		USING chat_merge cm
+		WHERE s.type = 'PV' AND s.chat_id = cm.chat_id AND EXISTS (
+			SELECT 1 FROM conversation_setting_dtos k
+			LEFT JOIN chat_merge km ON km.chat_id = k.chat_id
+			WHERE k.user_id = s.user_id AND k.type = 'PV'
+				AND COALESCE(km.keep_id, k.chat_id) = cm.keep_id AND k.chat_id < s.chat_id
+		)`,
+
+		`UPDATE conversation_setting_dtos s SET chat_id = cm.keep_id
+		FROM chat_merge cm
+		WHERE s.type = 'PV' AND s.chat_id = cm.chat_id`,
+
+		`DELETE FROM folder_entry_dtos e
+		USING chat_merge cm
+		WHERE e.type = 'PV' AND e.chat_id = cm.chat_id AND EXISTS (
+			SELECT 1 FROM folder_entry_dtos k
+			LEFT JOIN chat_merge km ON km.chat_id = k.chat_id
+			WHERE k.folder_id = e.folder_id AND k.type = 'PV'
+				AND COALESCE(km.keep_id, k.chat_id) = cm.keep_id AND k.chat_id < e.chat_id
+		)`,
+
+		`UPDATE folder_entry_dtos e SET chat_id = cm.keep_id
+		FROM chat_merge cm
+		WHERE e.type = 'PV' AND e.chat_id = cm.chat_id`,
+
+		`DELETE FROM user_chat_dtos c
+		USING chat_merge cm
+		WHERE c.chat_id = cm.chat_id`,
+
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_pair
+		ON user_chat_dtos (LEAST(user_id, receiver_id), GREATEST(user_id, receiver_id))`,
+	}
+
+	// Messages are keyed by conversation once keyMessagesByConversation has
+	// run, and by type and chat_id before that.
+	moveMessages := `UPDATE message_dtos m SET conversation_id = keep.conversation_id
+		FROM chat_merge cm
+		JOIN user_chat_dtos old ON old.chat_id = cm.chat_id
+		JOIN user_chat_dtos keep ON keep.chat_id = cm.keep_id
+		WHERE m.conversation_id = old.conversation_id`
+	if tx.Migrator().HasColumn("message_dtos", "chat_id") {
+		moveMessages = `UPDATE message_dtos m SET chat_id = cm.keep_id
+		FROM chat_merge cm
+		WHERE m.type = 'PV' AND m.chat_id = cm.chat_id`
+	}
+	statements = append(statements[:1], append([]string{moveMessages}, statements[1:]...)...)
+
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
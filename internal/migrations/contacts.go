@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// dropDuplicateContacts keeps one contact row per user and contact, a block
+// over an accepted contact over a pending request, so the unique index on
+// them can be created.
+func dropDuplicateContacts(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable("contact_dtos") {
+		return nil
+	}
+
+	return tx.Exec(`DELETE FROM contact_dtos c
+		USING (
+			SELECT contact_id, ROW_NUMBER() OVER (
+				PARTITION BY user_id, contact_user_id
+				ORDER BY CASE status WHEN 'blocked' THEN 0 WHEN 'accepted' THEN 1 ELSE 2 END, contact_id
+			) AS n
+			FROM contact_dtos
+		) ranked
+		WHERE ranked.contact_id = c.contact_id AND ranked.n > 1`).Error
+}
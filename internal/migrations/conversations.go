@@ -0,0 +1,108 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// conversationReferences are the tables whose conversation_id must point at
+// an existing conversation.
+var conversationReferences = []struct {
+	table      string
+	constraint string
+}{
+	{table: "user_chat_dtos", constraint: "fk_user_chat_conversation"},
+	{table: "group_dtos", constraint: "fk_group_conversation"},
+	{table: "channel_dtos", constraint: "fk_channel_conversation"},
+	{table: "message_dtos", constraint: "fk_message_conversation"},
+}
+
+// unifyConversations gives every chat, group and channel a row in
+// conversation_dtos, points their messages at it, links them to it with
+// foreign keys and drops the group_chat_dtos table that used to tie group
+// messages to their group. Messages whose chat was deleted get no
+// conversation; keyMessagesByConversation removes them.
+func unifyConversations(tx *gorm.DB) error {
+	statements := []string{
+		`INSERT INTO conversation_dtos (type, chat_id, created_at)
+		SELECT 'PV', chat_id, created_at FROM user_chat_dtos
+		UNION ALL
+		SELECT 'GP', group_id, created_at FROM group_dtos
+		UNION ALL
+		SELECT 'CH', channel_id, created_at FROM channel_dtos
+		ON CONFLICT (type, chat_id) DO NOTHING`,
+
+		`UPDATE user_chat_dtos u SET conversation_id = c.conversation_id
+		FROM conversation_dtos c
+		WHERE c.type = 'PV' AND c.chat_id = u.chat_id AND u.conversation_id IS NULL`,
+
+		`UPDATE group_dtos g SET conversation_id = c.conversation_id
+		FROM conversation_dtos c
+		WHERE c.type = 'GP' AND c.chat_id = g.group_id AND g.conversation_id IS NULL`,
+
+		`UPDATE channel_dtos ch SET conversation_id = c.conversation_id
+		FROM conversation_dtos c
+		WHERE c.type = 'CH' AND c.chat_id = ch.channel_id AND ch.conversation_id IS NULL`,
+
+		`DROP TABLE IF EXISTS group_chat_dtos`,
+	}
+
+	// Once keyMessagesByConversation has run, messages no longer carry the
+	// type and chat_id this backfill reads.
+	if tx.Migrator().HasColumn("message_dtos", "chat_id") {
+		statements = append(statements, `UPDATE message_dtos m SET conversation_id = c.conversation_id
+		FROM conversation_dtos c
+		WHERE c.type = m.type AND c.chat_id = m.chat_id AND m.conversation_id IS NULL`)
+	}
+
+	for _, ref := range conversationReferences {
+		statements = append(statements, fmt.Sprintf(`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = '%[2]s') THEN
+				ALTER TABLE %[1]s ADD CONSTRAINT %[2]s
+				FOREIGN KEY (conversation_id) REFERENCES conversation_dtos (conversation_id);
+			END IF;
+		END $$`, ref.table, ref.constraint))
+	}
+
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keyMessagesByConversation makes conversation_id the only link from a
+// message to its chat, group or channel. It runs once, while messages still
+// carry the type and chat_id columns: messages left without a conversation
+// because their chat was deleted are removed with their views, then
+// conversation_id becomes required and the old columns are dropped. Deleting
+// a chat, group or channel since removes its conversation and messages in the
+// same transaction.
+func keyMessagesByConversation(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn("message_dtos", "chat_id") {
+		return nil
+	}
+
+	statements := []string{
+		`DELETE FROM channel_view_dtos v
+		USING message_dtos m
+		WHERE v.message_id = m.message_id AND m.conversation_id IS NULL`,
+
+		`DELETE FROM message_dtos WHERE conversation_id IS NULL`,
+
+		`ALTER TABLE message_dtos ALTER COLUMN conversation_id SET NOT NULL`,
+
+		`ALTER TABLE message_dtos DROP COLUMN IF EXISTS type, DROP COLUMN IF EXISTS chat_id`,
+	}
+
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// assignGroupOwners makes the creator the owner of every group that has no
+// owner yet, which covers all groups created before membership roles.
+func assignGroupOwners(tx *gorm.DB) error {
+	return tx.Exec(`UPDATE user_group_dtos ug SET role = 'owner'
+		FROM group_dtos g
+		WHERE g.group_id = ug.group_id AND g.creator = ug.user_id AND NOT EXISTS (
+			SELECT 1 FROM user_group_dtos o WHERE o.group_id = ug.group_id AND o.role = 'owner'
+		)`).Error
+}
+
+// dropDuplicateMemberships keeps one membership per user and group, the one
+// with the highest role, so the unique index on them can be created.
+func dropDuplicateMemberships(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable("user_group_dtos") {
+		return nil
+	}
+
+	return tx.Exec(`DELETE FROM user_group_dtos ug
+		USING (
+			SELECT user_group_id, ROW_NUMBER() OVER (
+				PARTITION BY group_id, user_id
+				ORDER BY CASE role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 WHEN 'member' THEN 2 ELSE 3 END, user_group_id
+			) AS n
+			FROM user_group_dtos
+		) ranked
+		WHERE ranked.user_group_id = ug.user_group_id AND ranked.n > 1`).Error
+}
@@ -0,0 +1,59 @@
+// Package migrations holds the data migrations that AutoMigrate can not
+// express. Each step must be safe to run on every start.
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type step struct {
+	name string
+	run  func(tx *gorm.DB) error
+}
+
+var steps = []step{
+	{name: "merge duplicate private chats", run: mergeDuplicateChats},
+	{name: "unify conversations", run: unifyConversations},
+	{name: "key messages by conversation", run: keyMessagesByConversation},
+	{name: "assign group owners", run: assignGroupOwners},
+}
+
+// preparations clean up data that would stop AutoMigrate from adding a
+// constraint. They run before AutoMigrate, so the tables may not exist yet.
+var preparations = []step{
+	{name: "drop duplicate group memberships", run: dropDuplicateMemberships},
+	{name: "drop duplicate contacts", run: dropDuplicateContacts},
+}
+
+// Prepare applies every preparation in order, each in its own transaction.
+func Prepare(db *gorm.DB) error {
+	return apply(db, preparations)
+}
+
+// Run applies every migration step in order, each in its own transaction.
+func Run(db *gorm.DB) error {
+	return apply(db, steps)
+}
+
+func apply(db *gorm.DB, steps []step) error {
+	for _, s := range steps {
+		if err := db.Transaction(s.run); err != nil {
+			return &Error{Step: s.name, Err: err}
+		}
+	}
+
+	return nil
+}
+
+type Error struct {
+	Step string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return "migration " + e.Step + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type BookmarkKind string
+
+const (
+	KindBookmark BookmarkKind = "bookmark"
+	KindNote     BookmarkKind = "note"
+)
+
+type Bookmark struct {
+	BookmarkID  uint64          `gorm:"primaryKey;autoIncrement;not null" json:"bookmarkID"`
+	UserID      uint64          `gorm:"foreignKey;not null;index" json:"userID"`
+	Kind        BookmarkKind    `gorm:"type:varchar(20);not null" json:"kind"`
+	MessageID   uint64          `json:"messageID"`
+	ChatID      uint64          `json:"chatID"`
+	Type        MessageType     `json:"type"`
+	SenderID    uint64          `json:"senderID"`
+	MessageKind ContentKind     `gorm:"type:varchar(20);not null;default:'text'" json:"messageKind"`
+	Content     string          `gorm:"type:varchar(5000);not null" json:"content"`
+	Payload     json.RawMessage `gorm:"type:jsonb" json:"payload,omitempty"`
+	Note        string          `gorm:"type:varchar(1000)" json:"note"`
+	Tags        string          `gorm:"type:varchar(255)" json:"tags"`
+}
+
+type BookmarkInterface struct {
+	ID        *uint64
+	UserID    *uint64
+	MessageID *uint64
+	Kind      *BookmarkKind
+	Tag       *string
+	Query     *string
+}
+
+type BookmarkDTO struct {
+	Bookmark
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type BookmarkRepository interface {
+	Create(ctx context.Context, bookmark Bookmark) (uint64, error)
+	Get(ctx context.Context, bi BookmarkInterface) ([]BookmarkDTO, error)
+	// Update writes the note, tags and content as given, clearing those
+	// that are empty.
+	Update(ctx context.Context, bookmark Bookmark) error
+	Delete(ctx context.Context, bi BookmarkInterface) error
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type ChannelView struct {
+	ChannelViewID uint64 `gorm:"primaryKey;autoIncrement;not null" json:"channelViewID"`
+	MessageID     uint64 `gorm:"foreignKey;not null;uniqueIndex:idx_channel_view" json:"messageID"`
+	UserID        uint64 `gorm:"foreignKey;not null;uniqueIndex:idx_channel_view" json:"userID"`
+}
+
+type ChannelViewDTO struct {
+	ChannelView
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ChannelViewRepository interface {
+	Record(ctx context.Context, userID uint64, messageIDs []uint64) error
+	Count(ctx context.Context, messageIDs []uint64) (map[uint64]int64, error)
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type Channel struct {
+	ChannelID      uint64 `gorm:"primaryKey;autoIncrement;not null" json:"channelID"`
+	Name           string `gorm:"type:varchar(255);not null" json:"name"`
+	Description    string `gorm:"type:varchar(255);not null" json:"description"`
+	Handle         string `gorm:"type:varchar(32);not null;uniqueIndex" json:"handle"`
+	Owner          uint64 `gorm:"foreignKey;not null" json:"owner"`
+	ConversationID uint64 `gorm:"uniqueIndex" json:"conversationID"`
+}
+
+type ChannelInterface struct {
+	ID      *uint64
+	Handle  *string
+	OwnerID *uint64
+}
+
+type ChannelDTO struct {
+	Channel
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ChannelRepository interface {
+	Get(ctx context.Context, ci ChannelInterface) ([]Channel, error)
+	// Create makes the channel and subscribes its owner as such, in one
+	// transaction.
+	Create(ctx context.Context, channel Channel) (uint64, error)
+	Update(ctx context.Context, channel Channel) error
+	// Delete removes the matching channels together with their conversations,
+	// messages, views, subscribers, the subscribers' settings and folder
+	// entries, and community links.
+	Delete(ctx context.Context, ci ChannelInterface) error
+}
+
+func (c *ChannelDTO) ToChannel() *Channel {
+	return &Channel{
+		ChannelID:      c.ChannelID,
+		Name:           c.Name,
+		Description:    c.Description,
+		Handle:         c.Handle,
+		Owner:          c.Owner,
+		ConversationID: c.ConversationID,
+	}
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type CommunityRole string
+
+const (
+	CommunityOwner  CommunityRole = "owner"
+	CommunityAdmin  CommunityRole = "admin"
+	CommunityMember CommunityRole = "member"
+)
+
+// Community gathers related groups and channels under one membership.
+// Everyone in it is a member of its announcement group, where only
+// community admins can post.
+type Community struct {
+	CommunityID         uint64 `gorm:"primaryKey;autoIncrement;not null" json:"communityID"`
+	Name                string `gorm:"type:varchar(255);not null" json:"name"`
+	Description         string `gorm:"type:varchar(255);not null" json:"description"`
+	Owner               uint64 `gorm:"foreignKey;not null" json:"owner"`
+	AnnouncementGroupID uint64 `gorm:"foreignKey;not null" json:"announcementGroupID"`
+}
+
+type UserCommunity struct {
+	UserCommunityID uint64        `gorm:"primaryKey;autoIncrement;not null" json:"-"`
+	UserID          uint64        `gorm:"foreignKey;not null;uniqueIndex:idx_user_community;index" json:"userID"`
+	CommunityID     uint64        `gorm:"foreignKey;not null;uniqueIndex:idx_user_community" json:"communityID"`
+	Role            CommunityRole `gorm:"type:varchar(20);not null;default:'member'" json:"role"`
+}
+
+// CommunityLink puts a group or channel in a community. With AutoJoin set,
+// membership of the linked conversation follows community membership.
+type CommunityLink struct {
+	LinkID      uint64      `gorm:"primaryKey;autoIncrement;not null" json:"-"`
+	CommunityID uint64      `gorm:"foreignKey;not null;index" json:"communityID"`
+	Type        MessageType `gorm:"not null;uniqueIndex:idx_community_link" json:"type"`
+	ChatID      uint64      `gorm:"not null;uniqueIndex:idx_community_link" json:"chatID"`
+	AutoJoin    bool        `gorm:"not null;default:false" json:"autoJoin"`
+}
+
+type CommunityInterface struct {
+	ID      *uint64
+	OwnerID *uint64
+}
+
+type UserCommunityInterface struct {
+	CommunityID *uint64
+	UserID      *uint64
+	Role        *CommunityRole
+}
+
+type CommunityLinkInterface struct {
+	CommunityID *uint64
+	Type        *MessageType
+	ChatID      *uint64
+}
+
+type CommunityDTO struct {
+	Community
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UserCommunityDTO struct {
+	UserCommunity
+	CreatedAt time.Time `json:"joinedAt"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+type CommunityLinkDTO struct {
+	CommunityLink
+	CreatedAt time.Time `json:"-"`
+}
+
+type CommunityRepository interface {
+	// Create makes the community together with its announcement group and
+	// the owner's membership of both, in one transaction, and returns the
+	// ids of the community and the group.
+	Create(ctx context.Context, community Community, announcement Group) (uint64, uint64, error)
+	Get(ctx context.Context, ci CommunityInterface) ([]Community, error)
+	// GetForUser returns the communities userID is a member of.
+	GetForUser(ctx context.Context, userID uint64) ([]Community, error)
+	Update(ctx context.Context, community Community) error
+	// Delete removes the community with its members, links and announcement
+	// group in one transaction; the linked groups and channels stay.
+	Delete(ctx context.Context, communityID uint64) error
+	// AddMember adds the member, or does nothing when they already are one.
+	AddMember(ctx context.Context, member UserCommunity) error
+	GetMembers(ctx context.Context, uci UserCommunityInterface) ([]UserCommunityDTO, error)
+	CountMembers(ctx context.Context, communityID uint64) (int64, error)
+	SetMemberRole(ctx context.Context, communityID, userID uint64, role CommunityRole) error
+	RemoveMember(ctx context.Context, communityID, userID uint64) error
+	Link(ctx context.Context, link CommunityLink) error
+	GetLinks(ctx context.Context, cli CommunityLinkInterface) ([]CommunityLink, error)
+	Unlink(ctx context.Context, communityID uint64, chatType MessageType, chatID uint64) error
+}
+
+// CanManage reports whether the role may change the community and its
+// links.
+func (r CommunityRole) CanManage() bool {
+	return r == CommunityOwner || r == CommunityAdmin
+}
@@ -14,8 +14,8 @@ const (
 
 type Contact struct {
 	ContactID     uint64 `gorm:"primaryKey;autoIncrement;not null" json:"contactID"`
-	UserID        uint64 `gorm:"foreignKey;not null" json:"userID"`
-	ContactUserID uint64 `gorm:"foreignKey;not null" json:"contactUserID"`
+	UserID        uint64 `gorm:"foreignKey;not null;uniqueIndex:idx_contact_pair,priority:1" json:"userID"`
+	ContactUserID uint64 `gorm:"foreignKey;not null;uniqueIndex:idx_contact_pair,priority:2" json:"contactUserID"`
 	Status        Status `gorm:"default:'pending'" json:"status"`
 }
 
@@ -27,6 +27,8 @@ type ContactInterface struct {
 }
 
 type ContactRepository interface {
+	// Create inserts the contact unless the user already has a row for
+	// ContactUserID, in which case it does nothing.
 	Create(ctx context.Context, contact Contact) error
 	Get(ctx context.Context, ci ContactInterface) ([]Contact, error)
 	Update(ctx context.Context, contact Contact) error
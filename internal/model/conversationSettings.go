@@ -0,0 +1,45 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+const MaxPinnedConversations = 5
+
+type ConversationSetting struct {
+	SettingID    uint64      `gorm:"primaryKey;autoIncrement;not null" json:"-"`
+	UserID       uint64      `gorm:"foreignKey;not null;uniqueIndex:idx_conversation_setting" json:"userID"`
+	Type         MessageType `gorm:"not null;uniqueIndex:idx_conversation_setting" json:"type"`
+	ChatID       uint64      `gorm:"not null;uniqueIndex:idx_conversation_setting" json:"chatID"`
+	Archived     bool        `gorm:"not null;default:false" json:"archived"`
+	PinOrder     int         `gorm:"not null;default:0" json:"pinOrder"`
+	MutedUntil   *time.Time  `json:"mutedUntil"`
+	MarkedUnread bool        `gorm:"not null;default:false" json:"markedUnread"`
+}
+
+type ConversationSettingInterface struct {
+	UserID *uint64
+	Type   *MessageType
+	ChatID *uint64
+}
+
+type ConversationSettingDTO struct {
+	ConversationSetting
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ConversationSettingRepository interface {
+	Get(ctx context.Context, csi ConversationSettingInterface) ([]ConversationSetting, error)
+	Save(ctx context.Context, setting ConversationSetting) error
+	Delete(ctx context.Context, csi ConversationSettingInterface) error
+}
+
+func (s ConversationSetting) Pinned() bool {
+	return s.PinOrder > 0
+}
+
+func (s ConversationSetting) Muted(now time.Time) bool {
+	return s.MutedUntil != nil && s.MutedUntil.After(now)
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// Conversation is the single identity shared by a private chat, a group or a
+// channel. Messages reference it by ConversationID; Type and ChatID point back
+// to the user_chat, group or channel row it belongs to.
+type Conversation struct {
+	ConversationID uint64      `gorm:"primaryKey;autoIncrement;not null" json:"conversationID"`
+	Type           MessageType `gorm:"not null;uniqueIndex:idx_conversation_ref" json:"type"`
+	ChatID         uint64      `gorm:"not null;uniqueIndex:idx_conversation_ref" json:"chatID"`
+}
+
+type ConversationInterface struct {
+	ID     *uint64
+	Type   *MessageType
+	ChatID *uint64
+}
+
+type ConversationDTO struct {
+	Conversation
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ConversationRepository interface {
+	Get(ctx context.Context, ci ConversationInterface) ([]Conversation, error)
+	// Lookup returns the conversation of an existing chat, group or channel,
+	// or ErrConversationNotFound when there is no such row.
+	Lookup(ctx context.Context, chatType MessageType, chatID uint64) (uint64, error)
+}
+
+func (c *ConversationDTO) ToConversation() *Conversation {
+	return &Conversation{
+		ConversationID: c.ConversationID,
+		Type:           c.Type,
+		ChatID:         c.ChatID,
+	}
+}
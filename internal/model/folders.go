@@ -0,0 +1,61 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type FolderMode string
+
+const (
+	FolderInclude FolderMode = "include"
+	FolderExclude FolderMode = "exclude"
+)
+
+type Folder struct {
+	FolderID     uint64 `gorm:"primaryKey;autoIncrement;not null" json:"folderID"`
+	UserID       uint64 `gorm:"foreignKey;not null;index" json:"userID"`
+	Name         string `gorm:"type:varchar(64);not null" json:"name"`
+	Position     int    `gorm:"not null;default:0" json:"position"`
+	UnreadOnly   bool   `gorm:"not null;default:false" json:"unreadOnly"`
+	GroupsOnly   bool   `gorm:"not null;default:false" json:"groupsOnly"`
+	ContactsOnly bool   `gorm:"not null;default:false" json:"contactsOnly"`
+	ExcludeMuted bool   `gorm:"not null;default:false" json:"excludeMuted"`
+}
+
+type FolderEntry struct {
+	FolderEntryID uint64      `gorm:"primaryKey;autoIncrement;not null" json:"-"`
+	FolderID      uint64      `gorm:"foreignKey;not null;index" json:"-"`
+	Type          MessageType `gorm:"not null" json:"type"`
+	ChatID        uint64      `gorm:"not null" json:"chatID"`
+	Mode          FolderMode  `gorm:"type:varchar(10);not null" json:"mode"`
+}
+
+type FolderInterface struct {
+	ID     *uint64
+	UserID *uint64
+}
+
+type FolderDTO struct {
+	Folder
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type FolderEntryDTO struct {
+	FolderEntry
+	CreatedAt time.Time `json:"-"`
+}
+
+type FolderRepository interface {
+	// Create makes the folder with its include/exclude lists, in one
+	// transaction.
+	Create(ctx context.Context, folder Folder, entries []FolderEntry) (uint64, error)
+	Get(ctx context.Context, fi FolderInterface) ([]Folder, error)
+	Update(ctx context.Context, folder Folder) error
+	// Replace is Update that also replaces the include/exclude lists, in
+	// one transaction.
+	Replace(ctx context.Context, folder Folder, entries []FolderEntry) error
+	Delete(ctx context.Context, fi FolderInterface) error
+	GetEntries(ctx context.Context, folderIDs []uint64) ([]FolderEntry, error)
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type GroupBan struct {
+	BanID     uint64     `gorm:"primaryKey;autoIncrement;not null" json:"banID"`
+	GroupID   uint64     `gorm:"foreignKey;not null;uniqueIndex:idx_group_ban" json:"groupID"`
+	UserID    uint64     `gorm:"foreignKey;not null;uniqueIndex:idx_group_ban" json:"userID"`
+	BannedBy  uint64     `gorm:"foreignKey;not null" json:"bannedBy"`
+	Reason    string     `gorm:"type:varchar(500)" json:"reason"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type GroupBanInterface struct {
+	GroupID *uint64
+	UserID  *uint64
+}
+
+type GroupBanDTO struct {
+	GroupBan
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type GroupBanRepository interface {
+	// Save creates the ban or replaces the existing one for the same user.
+	Save(ctx context.Context, ban GroupBan) error
+	Get(ctx context.Context, gbi GroupBanInterface) ([]GroupBanDTO, error)
+	Delete(ctx context.Context, gbi GroupBanInterface) error
+}
+
+// Active reports whether the ban still applies at now. A ban without an
+// expiry is permanent.
+func (b GroupBan) Active(now time.Time) bool {
+	return b.ExpiresAt == nil || now.Before(*b.ExpiresAt)
+}
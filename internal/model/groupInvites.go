@@ -0,0 +1,52 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type GroupInvite struct {
+	InviteID         uint64     `gorm:"primaryKey;autoIncrement;not null" json:"inviteID"`
+	GroupID          uint64     `gorm:"foreignKey;not null;index" json:"groupID"`
+	Token            string     `gorm:"type:varchar(32);not null;uniqueIndex" json:"token"`
+	CreatedBy        uint64     `gorm:"foreignKey;not null" json:"createdBy"`
+	ExpiresAt        *time.Time `json:"expiresAt"`
+	MaxUses          int        `gorm:"not null;default:0" json:"maxUses"`
+	Uses             int        `gorm:"not null;default:0" json:"uses"`
+	RequiresApproval bool       `gorm:"not null;default:false" json:"requiresApproval"`
+	Revoked          bool       `gorm:"not null;default:false" json:"revoked"`
+}
+
+type GroupInviteInterface struct {
+	ID      *uint64
+	GroupID *uint64
+	Token   *string
+}
+
+type GroupInviteDTO struct {
+	GroupInvite
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type GroupInviteRepository interface {
+	Create(ctx context.Context, invite GroupInvite) (uint64, error)
+	Get(ctx context.Context, gii GroupInviteInterface) ([]GroupInviteDTO, error)
+	Revoke(ctx context.Context, inviteID uint64) error
+	// Use counts one join against the invite. It reports false, without
+	// counting, when the invite is revoked, expired or used up.
+	Use(ctx context.Context, inviteID uint64) (bool, error)
+}
+
+// Valid reports whether the invite can still be used at now. A MaxUses of
+// zero means unlimited.
+func (i GroupInvite) Valid(now time.Time) bool {
+	if i.Revoked {
+		return false
+	}
+	if i.ExpiresAt != nil && !now.Before(*i.ExpiresAt) {
+		return false
+	}
+
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
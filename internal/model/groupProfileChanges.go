@@ -0,0 +1,40 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type GroupProfileField string
+
+const (
+	GroupFieldName        GroupProfileField = "name"
+	GroupFieldDescription GroupProfileField = "description"
+	GroupFieldAvatar      GroupProfileField = "avatar"
+)
+
+type GroupProfileChange struct {
+	ChangeID  uint64            `gorm:"primaryKey;autoIncrement;not null" json:"changeID"`
+	GroupID   uint64            `gorm:"foreignKey;not null;index" json:"groupID"`
+	ChangedBy uint64            `gorm:"foreignKey;not null" json:"changedBy"`
+	Field     GroupProfileField `gorm:"type:varchar(20);not null" json:"field"`
+	OldValue  string            `gorm:"type:varchar(255)" json:"oldValue"`
+	NewValue  string            `gorm:"type:varchar(255)" json:"newValue"`
+}
+
+type GroupProfileChangeInterface struct {
+	GroupID *uint64
+	Field   *GroupProfileField
+}
+
+type GroupProfileChangeDTO struct {
+	GroupProfileChange
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type GroupProfileChangeRepository interface {
+	Create(ctx context.Context, changes []GroupProfileChange) error
+	// Get returns the matching changes, newest first.
+	Get(ctx context.Context, gpci GroupProfileChangeInterface) ([]GroupProfileChangeDTO, error)
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// GeneralTopic is the topic of messages sent outside any named topic.
+const GeneralTopic uint64 = 0
+
+type GroupTopic struct {
+	TopicID         uint64 `gorm:"primaryKey;autoIncrement;not null" json:"topicID"`
+	GroupID         uint64 `gorm:"foreignKey;not null;index" json:"groupID"`
+	Title           string `gorm:"type:varchar(128);not null" json:"title"`
+	CreatedBy       uint64 `gorm:"foreignKey;not null" json:"createdBy"`
+	Closed          bool   `gorm:"not null;default:false" json:"closed"`
+	PinnedMessageID uint64 `json:"pinnedMessageID,omitempty"`
+}
+
+type GroupTopicInterface struct {
+	ID      *uint64
+	GroupID *uint64
+}
+
+type GroupTopicDTO struct {
+	GroupTopic
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GroupTopicSummary is a topic as listed for one member, with its latest
+// message and how much of it is unread. Like the rest of group messaging,
+// Unread goes by the message's shared read flag rather than a read marker
+// per member: it counts messages from others that nobody has marked read.
+type GroupTopicSummary struct {
+	GroupTopicDTO
+	LastMessageID uint64    `json:"lastMessageID,omitempty"`
+	Unread        int64     `json:"unread"`
+	ActivityAt    time.Time `json:"activityAt"`
+}
+
+type GroupTopicRepository interface {
+	Create(ctx context.Context, topic GroupTopic) (uint64, error)
+	Get(ctx context.Context, gti GroupTopicInterface) ([]GroupTopicDTO, error)
+	// Update writes the title, closed state and pin of the topic as given.
+	Update(ctx context.Context, topic GroupTopic) error
+	// Delete removes the matching topics together with their messages, in
+	// one transaction.
+	Delete(ctx context.Context, gti GroupTopicInterface) error
+	// List returns the topics of groupID for userID, most recently active
+	// first. See GroupTopicSummary for what Unread counts.
+	List(ctx context.Context, groupID, userID uint64) ([]GroupTopicSummary, error)
+}
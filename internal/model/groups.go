@@ -6,10 +6,18 @@ import (
 )
 
 type Group struct {
-	GroupID     uint64 `gorm:"primaryKey;auto_increment;not null" json:"groupID"`
-	Name        string `gorm:"type:varchar(255);not null" json:"name"`
-	Description string `gorm:"type:varchar(255);not null" json:"description"`
-	Creator     uint64 `gorm:"foreignKey;not null" json:"creator"`
+	GroupID          uint64 `gorm:"primaryKey;auto_increment;not null" json:"groupID"`
+	Name             string `gorm:"type:varchar(255);not null" json:"name"`
+	Description      string `gorm:"type:varchar(255);not null" json:"description"`
+	Avatar           string `gorm:"type:varchar(255)" json:"avatar"`
+	Creator          uint64 `gorm:"foreignKey;not null" json:"creator"`
+	ConversationID   uint64 `gorm:"uniqueIndex" json:"conversationID"`
+	RequiresApproval bool   `gorm:"not null;default:false" json:"requiresApproval"`
+	SlowModeSeconds  int    `gorm:"not null;default:0" json:"slowModeSeconds"`
+	ForumMode        bool   `gorm:"not null;default:false" json:"forumMode"`
+	Rules            string `gorm:"type:text;not null;default:''" json:"rules"`
+	WelcomeMessage   string `gorm:"type:varchar(1000);not null;default:''" json:"welcomeMessage"`
+	RulesRequired    bool   `gorm:"not null;default:false" json:"rulesRequired"`
 }
 
 type GroupInterface struct {
@@ -28,14 +36,36 @@ type GroupRepository interface {
 	Get(ctx context.Context, gi GroupInterface) ([]Group, error)
 	Create(ctx context.Context, group Group) (uint64, error)
 	Update(ctx context.Context, group Group) error
+	// Delete removes the matching groups together with their memberships,
+	// invites, join requests, bans, topics, profile history, conversations
+	// and messages, and the settings, folder entries and community links
+	// pointing at them.
 	Delete(ctx context.Context, gi GroupInterface) error
+	SetRequiresApproval(ctx context.Context, groupID uint64, required bool) error
+	SetSlowMode(ctx context.Context, groupID uint64, seconds int) error
+	SetForumMode(ctx context.Context, groupID uint64, enabled bool) error
+	// UpdateProfile writes name, description and avatar as given, so
+	// unlike Update it can clear the description.
+	UpdateProfile(ctx context.Context, group Group) error
+	// SetRules writes the rules, welcome template and whether the rules
+	// must be accepted, clearing those left empty. With resetAcceptance
+	// every member's acceptance is cleared in the same transaction.
+	SetRules(ctx context.Context, group Group, resetAcceptance bool) error
 }
 
 func (g *GroupDTO) ToGroup() *Group {
 	return &Group{
-		GroupID:     g.GroupID,
-		Name:        g.Name,
-		Description: g.Description,
-		Creator:     g.Creator,
+		GroupID:          g.GroupID,
+		Name:             g.Name,
+		Description:      g.Description,
+		Avatar:           g.Avatar,
+		Creator:          g.Creator,
+		ConversationID:   g.ConversationID,
+		RequiresApproval: g.RequiresApproval,
+		SlowModeSeconds:  g.SlowModeSeconds,
+		ForumMode:        g.ForumMode,
+		Rules:            g.Rules,
+		WelcomeMessage:   g.WelcomeMessage,
+		RulesRequired:    g.RulesRequired,
 	}
 }
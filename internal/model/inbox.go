@@ -0,0 +1,62 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type InboxCursor struct {
+	ActivityAt time.Time
+	Type       MessageType
+	ChatID     uint64
+}
+
+type InboxQuery struct {
+	UserID   uint64
+	Archived bool
+	Pinned   bool
+	Cursor   *InboxCursor
+	Limit    int
+}
+
+type InboxPeer struct {
+	UserID         uint64    `json:"userID"`
+	Name           string    `json:"name"`
+	Username       string    `json:"username"`
+	ProfilePicture string    `json:"profilePicture"`
+	IsActive       string    `json:"isActive"`
+	LastSeen       time.Time `json:"lastSeen"`
+}
+
+type InboxGroup struct {
+	GroupID     uint64 `json:"groupID"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Avatar      string `json:"avatar"`
+}
+
+type InboxMessage struct {
+	MessageID uint64      `json:"messageID"`
+	SenderID  uint64      `json:"senderID"`
+	Kind      ContentKind `json:"kind"`
+	Preview   string      `json:"preview"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type InboxItem struct {
+	Type         MessageType   `json:"type"`
+	ChatID       uint64        `json:"chatID"`
+	Peer         *InboxPeer    `json:"peer,omitempty"`
+	Group        *InboxGroup   `json:"group,omitempty"`
+	LastMessage  *InboxMessage `json:"lastMessage,omitempty"`
+	Unread       int64         `json:"unread"`
+	Mentions     int64         `json:"mentions"`
+	PinOrder     int           `json:"pinOrder"`
+	MutedUntil   *time.Time    `json:"mutedUntil"`
+	MarkedUnread bool          `json:"markedUnread"`
+	ActivityAt   time.Time     `json:"activityAt"`
+}
+
+type InboxRepository interface {
+	List(ctx context.Context, iq InboxQuery) ([]InboxItem, error)
+}
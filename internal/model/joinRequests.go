@@ -0,0 +1,46 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type JoinRequestStatus string
+
+const (
+	JoinPending  JoinRequestStatus = "pending"
+	JoinApproved JoinRequestStatus = "approved"
+	JoinDeclined JoinRequestStatus = "declined"
+)
+
+type JoinRequest struct {
+	RequestID uint64            `gorm:"primaryKey;autoIncrement;not null" json:"requestID"`
+	GroupID   uint64            `gorm:"foreignKey;not null;index:idx_join_request_group" json:"groupID"`
+	UserID    uint64            `gorm:"foreignKey;not null;index" json:"userID"`
+	Message   string            `gorm:"type:varchar(500)" json:"message"`
+	InviteID  uint64            `json:"inviteID,omitempty"`
+	Status    JoinRequestStatus `gorm:"type:varchar(20);not null;default:'pending';index:idx_join_request_group" json:"status"`
+	DecidedBy uint64            `json:"decidedBy,omitempty"`
+	DecidedAt *time.Time        `json:"decidedAt,omitempty"`
+}
+
+type JoinRequestInterface struct {
+	ID      *uint64
+	GroupID *uint64
+	UserID  *uint64
+	Status  *JoinRequestStatus
+}
+
+type JoinRequestDTO struct {
+	JoinRequest
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type JoinRequestRepository interface {
+	Create(ctx context.Context, request JoinRequest) (uint64, error)
+	Get(ctx context.Context, jri JoinRequestInterface) ([]JoinRequestDTO, error)
+	// Decide moves a pending request to status. It reports false when the
+	// request was no longer pending, e.g. another admin decided first.
+	Decide(ctx context.Context, requestID uint64, status JoinRequestStatus, decidedBy uint64) (bool, error)
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 )
 
@@ -10,31 +11,54 @@ type MessageType string
 const (
 	TypePV MessageType = "PV"
 	TypeGP MessageType = "GP"
+	TypeCH MessageType = "CH"
 )
 
+// Message belongs to a conversation through ConversationID. Type and ChatID
+// are read from that conversation and are not stored on the message; they
+// are still given when writing, to name the chat, group or channel. The
+// migrations make conversation_id NOT NULL once old rows are backfilled.
 type Message struct {
-	MessageID uint64      `gorm:"primaryKey;autoIncrement;not null" json:"messageID"`
-	ChatID    uint64      `gorm:"foreignKey;not null" json:"chatID"`
-	SenderID  uint64      `gorm:"foreignKey;not null" json:"senderID"`
-	Type      MessageType `gorm:"not null" json:"type"`
-	Content   string      `gorm:"type:varchar(5000);not null" json:"content"`
-	IsRead    string      `gorm:"type:varchar(10);" json:"isRead"`
+	MessageID      uint64          `gorm:"primaryKey;autoIncrement;not null;index:idx_message_conversation,priority:2;index:idx_message_topic,priority:3" json:"messageID"`
+	ConversationID uint64          `gorm:"index:idx_message_conversation,priority:1;index:idx_message_topic,priority:1" json:"conversationID"`
+	ChatID         uint64          `gorm:"->;-:migration" json:"chatID"`
+	TopicID        uint64          `gorm:"not null;default:0;index:idx_message_topic,priority:2" json:"topicID,omitempty"`
+	SenderID       uint64          `gorm:"foreignKey;not null" json:"senderID"`
+	Type           MessageType     `gorm:"->;-:migration" json:"type"`
+	Kind           ContentKind     `gorm:"type:varchar(20);not null;default:'text'" json:"kind"`
+	Content        string          `gorm:"type:varchar(5000);not null" json:"content"`
+	Payload        json.RawMessage `gorm:"type:jsonb" json:"payload,omitempty"`
+	ReplyToID      uint64          `json:"replyToID,omitempty"`
+	IsRead         string          `gorm:"type:varchar(10);" json:"isRead"`
 }
 
 type MessageInterface struct {
-	ID       *uint64
-	ChatID   *uint64
-	SenderID *uint64
-	Type     *MessageType
-	IsRead   *string
+	ID             *uint64
+	ConversationID *uint64
+	ChatID         *uint64
+	TopicID        *uint64
+	SenderID       *uint64
+	Type           *MessageType
+	Kind           *ContentKind
+	IsRead         *string
 }
 
 type MessageRepository interface {
+	// Create fails with ErrConversationNotFound unless the chat, group or
+	// channel the message is addressed to exists.
 	Create(ctx context.Context, message Message) (uint64, error)
 	Get(ctx context.Context, mi MessageInterface) ([]Message, error)
 	Update(ctx context.Context, message Message) error
 	Delete(ctx context.Context, mi MessageInterface) error
 	GetDto(ctx context.Context, mi MessageInterface) ([]MessageDTO, error)
+	GetPage(ctx context.Context, mi MessageInterface, before *uint64, limit int) ([]MessageDTO, error)
+	// GetRange returns up to limit messages sent in [from, to) with an ID
+	// above after, oldest first.
+	GetRange(ctx context.Context, mi MessageInterface, from, to *time.Time, after *uint64, limit int) ([]MessageDTO, error)
+	CreateBatch(ctx context.Context, messages []MessageDTO) ([]uint64, error)
+	SetReplies(ctx context.Context, replies map[uint64]uint64) error
+	CountUnread(ctx context.Context, userID uint64, chatType MessageType, chatIDs []uint64) (map[uint64]int64, error)
+	Search(ctx context.Context, ms MessageSearch) ([]MessageSearchResult, error)
 }
 
 type MessageDTO struct {
@@ -42,3 +66,34 @@ type MessageDTO struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+type SearchCursor struct {
+	Rank      float32
+	MessageID uint64
+}
+
+// MessageSearch looks for Query in what UserID can read. ChatID narrows it
+// to one conversation and is only meaningful together with Type.
+type MessageSearch struct {
+	Query    string
+	UserID   uint64
+	ChatID   *uint64
+	SenderID *uint64
+	Type     *MessageType
+	Kind     *ContentKind
+	From     *time.Time
+	To       *time.Time
+	Cursor   *SearchCursor
+	Limit    int
+}
+
+type MessageSearchResult struct {
+	MessageID uint64      `json:"messageID"`
+	ChatID    uint64      `json:"chatID"`
+	SenderID  uint64      `json:"senderID"`
+	Type      MessageType `json:"type"`
+	Kind      ContentKind `json:"kind"`
+	Snippet   string      `json:"snippet"`
+	Rank      float32     `json:"rank"`
+	CreatedAt time.Time   `json:"created_at"`
+}
@@ -0,0 +1,241 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type ContentKind string
+
+const (
+	KindText     ContentKind = "text"
+	KindImage    ContentKind = "image"
+	KindFile     ContentKind = "file"
+	KindVoice    ContentKind = "voice"
+	KindLocation ContentKind = "location"
+	KindContact  ContentKind = "contact"
+	KindSticker  ContentKind = "sticker"
+	KindSystem   ContentKind = "system"
+)
+
+type ImagePayload struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mimeType"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+type FilePayload struct {
+	URL      string `json:"url"`
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+}
+
+type VoicePayload struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mimeType"`
+	Duration int    `json:"duration"`
+}
+
+type LocationPayload struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Title     string  `json:"title"`
+}
+
+type ContactPayload struct {
+	UserID uint64 `json:"userID"`
+	Name   string `json:"name"`
+	Phone  string `json:"phone"`
+}
+
+type StickerPayload struct {
+	StickerID string `json:"stickerID"`
+	SetName   string `json:"setName"`
+	Emoji     string `json:"emoji"`
+}
+
+// Events carried by system messages.
+const (
+	SystemGroupCreated         = "group_created"
+	SystemMemberAdded          = "member_added"
+	SystemMemberJoined         = "member_joined"
+	SystemMemberLeft           = "member_left"
+	SystemMemberRemoved        = "member_removed"
+	SystemMemberBanned         = "member_banned"
+	SystemOwnershipTransferred = "ownership_transferred"
+	SystemTitleChanged         = "title_changed"
+	SystemDescriptionChanged   = "description_changed"
+	SystemAvatarChanged        = "avatar_changed"
+	SystemMessagePinned        = "message_pinned"
+	SystemTopicCreated         = "topic_created"
+	SystemTopicClosed          = "topic_closed"
+	SystemTopicReopened        = "topic_reopened"
+	SystemWelcome              = "welcome"
+)
+
+// SystemPayload describes a change to a group: who made it (actor), who it
+// was made to (target), and for edits the old and new values.
+type SystemPayload struct {
+	Event     string `json:"event"`
+	ActorID   uint64 `json:"actorID"`
+	TargetID  uint64 `json:"targetID,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Old       string `json:"old,omitempty"`
+	New       string `json:"new,omitempty"`
+	MessageID uint64 `json:"messageID,omitempty"`
+	TopicID   uint64 `json:"topicID,omitempty"`
+}
+
+var (
+	ErrUnknownKind    = errors.New("unknown message kind")
+	ErrEmptyContent   = errors.New("message content can not be empty")
+	ErrMissingPayload = errors.New("message payload is required for this kind")
+	ErrSystemKind     = errors.New("system messages can not be sent by users")
+)
+
+func (k ContentKind) Valid() bool {
+	switch k {
+	case KindText, KindImage, KindFile, KindVoice, KindLocation, KindContact, KindSticker, KindSystem:
+		return true
+	}
+
+	return false
+}
+
+func decodePayload(payload json.RawMessage, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid message payload: %w", err)
+	}
+	// The payload must be exactly one JSON value; anything after it would
+	// only fail later, in the jsonb column.
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return errors.New("invalid message payload: unexpected data after the payload")
+	}
+
+	return nil
+}
+
+// NormalizePayload returns nil for a payload that carries nothing: empty,
+// blank or JSON null, as WebSocket clients send for text messages.
+func NormalizePayload(payload json.RawMessage) json.RawMessage {
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil
+	}
+
+	return payload
+}
+
+// ValidateMessageContent checks content and payload against the schema of
+// kind. Text messages carry no payload; every other kind must have one, and
+// content is then used as an optional caption.
+func ValidateMessageContent(kind ContentKind, content string, payload json.RawMessage) error {
+	payload = NormalizePayload(payload)
+	if !kind.Valid() {
+		return ErrUnknownKind
+	}
+
+	if kind == KindText {
+		if content == "" {
+			return ErrEmptyContent
+		}
+		if len(payload) != 0 {
+			return errors.New("text messages can not have a payload")
+		}
+		return nil
+	}
+
+	if len(payload) == 0 {
+		return ErrMissingPayload
+	}
+
+	switch kind {
+	case KindImage:
+		var p ImagePayload
+		if err := decodePayload(payload, &p); err != nil {
+			return err
+		}
+		if p.URL == "" {
+			return errors.New("image payload requires url")
+		}
+		if p.Width < 0 || p.Height < 0 {
+			return errors.New("image dimensions can not be negative")
+		}
+	case KindFile:
+		var p FilePayload
+		if err := decodePayload(payload, &p); err != nil {
+			return err
+		}
+		if p.URL == "" || p.Name == "" {
+			return errors.New("file payload requires url and name")
+		}
+		if p.Size < 0 {
+			return errors.New("file size can not be negative")
+		}
+	case KindVoice:
+		var p VoicePayload
+		if err := decodePayload(payload, &p); err != nil {
+			return err
+		}
+		if p.URL == "" {
+			return errors.New("voice payload requires url")
+		}
+		if p.Duration <= 0 {
+			return errors.New("voice duration must be positive")
+		}
+	case KindLocation:
+		var p LocationPayload
+		if err := decodePayload(payload, &p); err != nil {
+			return err
+		}
+		if p.Latitude < -90 || p.Latitude > 90 || p.Longitude < -180 || p.Longitude > 180 {
+			return errors.New("location coordinates are out of range")
+		}
+	case KindContact:
+		var p ContactPayload
+		if err := decodePayload(payload, &p); err != nil {
+			return err
+		}
+		if p.Name == "" || (p.UserID == 0 && p.Phone == "") {
+			return errors.New("contact payload requires name and userID or phone")
+		}
+	case KindSticker:
+		var p StickerPayload
+		if err := decodePayload(payload, &p); err != nil {
+			return err
+		}
+		if p.StickerID == "" {
+			return errors.New("sticker payload requires stickerID")
+		}
+	case KindSystem:
+		var p SystemPayload
+		if err := decodePayload(payload, &p); err != nil {
+			return err
+		}
+		if p.Event == "" {
+			return errors.New("system payload requires event")
+		}
+	}
+
+	return nil
+}
+
+// ValidateUserMessage is ValidateMessageContent for messages sent by clients,
+// which may not forge system messages. An empty kind means text.
+func ValidateUserMessage(kind ContentKind, content string, payload json.RawMessage) (ContentKind, error) {
+	if kind == "" {
+		kind = KindText
+	}
+	if kind == KindSystem {
+		return kind, ErrSystemKind
+	}
+
+	return kind, ValidateMessageContent(kind, content, payload)
+}
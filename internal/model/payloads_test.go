@@ -0,0 +1,95 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestValidateMessageContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    ContentKind
+		content string
+		payload string
+		wantErr error
+		ok      bool
+	}{
+		{name: "text", kind: KindText, content: "hi", ok: true},
+		{name: "text with null payload", kind: KindText, content: "hi", payload: " null ", ok: true},
+		{name: "empty text", kind: KindText, wantErr: ErrEmptyContent},
+		{name: "text with payload", kind: KindText, content: "hi", payload: `{"url":"x"}`},
+		{name: "unknown kind", kind: "video", content: "hi", wantErr: ErrUnknownKind},
+		{name: "image without payload", kind: KindImage, wantErr: ErrMissingPayload},
+		{name: "image", kind: KindImage, payload: `{"url":"https://x/a.png","width":10,"height":20}`, ok: true},
+		{name: "image caption", kind: KindImage, content: "look", payload: `{"url":"https://x/a.png"}`, ok: true},
+		{name: "image without url", kind: KindImage, payload: `{"width":10}`},
+		{name: "image negative size", kind: KindImage, payload: `{"url":"u","width":-1}`},
+		{name: "unknown field", kind: KindImage, payload: `{"url":"u","alt":"a"}`},
+		{name: "trailing data", kind: KindImage, payload: `{"url":"u"} {"url":"v"}`},
+		{name: "trailing garbage", kind: KindImage, payload: `{"url":"u"}x`},
+		{name: "not an object", kind: KindImage, payload: `"u"`},
+		{name: "file", kind: KindFile, payload: `{"url":"u","name":"a.pdf","size":3}`, ok: true},
+		{name: "file without name", kind: KindFile, payload: `{"url":"u"}`},
+		{name: "file negative size", kind: KindFile, payload: `{"url":"u","name":"a","size":-1}`},
+		{name: "voice", kind: KindVoice, payload: `{"url":"u","duration":4}`, ok: true},
+		{name: "voice without duration", kind: KindVoice, payload: `{"url":"u"}`},
+		{name: "location", kind: KindLocation, payload: `{"latitude":51.5,"longitude":-0.1}`, ok: true},
+		{name: "location out of range", kind: KindLocation, payload: `{"latitude":91,"longitude":0}`},
+		{name: "contact by user", kind: KindContact, payload: `{"userID":7,"name":"Ann"}`, ok: true},
+		{name: "contact by phone", kind: KindContact, payload: `{"phone":"+100","name":"Ann"}`, ok: true},
+		{name: "contact without reference", kind: KindContact, payload: `{"name":"Ann"}`},
+		{name: "sticker", kind: KindSticker, payload: `{"stickerID":"s1"}`, ok: true},
+		{name: "sticker without id", kind: KindSticker, payload: `{"emoji":"x"}`},
+		{name: "system", kind: KindSystem, payload: `{"event":"welcome","actorID":1}`, ok: true},
+		{name: "system without event", kind: KindSystem, payload: `{"actorID":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload json.RawMessage
+			if tt.payload != "" {
+				payload = json.RawMessage(tt.payload)
+			}
+
+			err := ValidateMessageContent(tt.kind, tt.content, payload)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateUserMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     ContentKind
+		wantKind ContentKind
+		wantErr  error
+	}{
+		{name: "empty kind is text", kind: "", wantKind: KindText},
+		{name: "text", kind: KindText, wantKind: KindText},
+		{name: "system is refused", kind: KindSystem, wantKind: KindSystem, wantErr: ErrSystemKind},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, err := ValidateUserMessage(tt.kind, "hi", nil)
+			if kind != tt.wantKind {
+				t.Fatalf("kind = %q, want %q", kind, tt.wantKind)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
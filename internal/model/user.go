@@ -28,6 +28,7 @@ type UserInterface struct {
 type UserRepository interface {
 	Create(ctx context.Context, user User) error
 	Get(ctx context.Context, ui UserInterface) ([]User, error)
+	GetMany(ctx context.Context, userIDs []uint64) ([]User, error)
 	Update(ctx context.Context, user User) error
 	Delete(ctx context.Context, ui UserInterface) error
 }
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type ChannelRole string
+
+const (
+	ChannelOwner      ChannelRole = "owner"
+	ChannelAdmin      ChannelRole = "admin"
+	ChannelSubscriber ChannelRole = "subscriber"
+)
+
+type UserChannel struct {
+	UserChannelID uint64      `gorm:"primaryKey;autoIncrement;not null" json:"userChannelID"`
+	UserID        uint64      `gorm:"foreignKey;not null;uniqueIndex:idx_user_channel" json:"userID"`
+	ChannelID     uint64      `gorm:"foreignKey;not null;uniqueIndex:idx_user_channel;index" json:"channelID"`
+	Role          ChannelRole `gorm:"type:varchar(20);not null;default:'subscriber'" json:"role"`
+	// CommunityID is set when joining a community subscribed the user.
+	CommunityID uint64 `gorm:"index" json:"communityID,omitempty"`
+}
+
+type UserChannelInterface struct {
+	ID        *uint64
+	UserID    *uint64
+	ChannelID *uint64
+	Role      *ChannelRole
+}
+
+type UserChannelDTO struct {
+	UserChannel
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UserChannelRepository interface {
+	Create(ctx context.Context, userChannel UserChannel) error
+	Get(ctx context.Context, uci UserChannelInterface) ([]UserChannel, error)
+	Update(ctx context.Context, userChannel UserChannel) error
+	Delete(ctx context.Context, uci UserChannelInterface) error
+	Count(ctx context.Context, channelID uint64) (int64, error)
+}
+
+func (r ChannelRole) CanPost() bool {
+	return r == ChannelOwner || r == ChannelAdmin
+}
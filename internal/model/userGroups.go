@@ -5,16 +5,91 @@ import (
 	"time"
 )
 
+type GroupRole string
+
+const (
+	GroupOwner      GroupRole = "owner"
+	GroupAdmin      GroupRole = "admin"
+	GroupMember     GroupRole = "member"
+	GroupRestricted GroupRole = "restricted"
+)
+
+// GroupAction is something a member may try to do in a group. Whether they
+// may is decided by UserGroup.Can.
+type GroupAction string
+
+const (
+	GroupRead           GroupAction = "read"
+	GroupSendMessages   GroupAction = "send_messages"
+	GroupAddMembers     GroupAction = "add_members"
+	GroupRemoveMembers  GroupAction = "remove_members"
+	GroupDeleteMessages GroupAction = "delete_messages"
+	GroupPinMessages    GroupAction = "pin_messages"
+	GroupEditInfo       GroupAction = "edit_info"
+	GroupManageTopics   GroupAction = "manage_topics"
+	GroupManageAdmins   GroupAction = "manage_admins"
+	GroupDelete         GroupAction = "delete_group"
+	GroupTransfer       GroupAction = "transfer_ownership"
+)
+
+// GroupPermissions are the flags the owner grants to each admin. They mean
+// nothing for other roles.
+type GroupPermissions struct {
+	CanAddMembers     bool `gorm:"not null;default:false" json:"canAddMembers"`
+	CanRemoveMembers  bool `gorm:"not null;default:false" json:"canRemoveMembers"`
+	CanDeleteMessages bool `gorm:"not null;default:false" json:"canDeleteMessages"`
+	CanPinMessages    bool `gorm:"not null;default:false" json:"canPinMessages"`
+	CanEditInfo       bool `gorm:"not null;default:false" json:"canEditInfo"`
+	CanManageTopics   bool `gorm:"not null;default:false" json:"canManageTopics"`
+}
+
 type UserGroup struct {
-	UserGroupID uint64 `gorm:"primaryKey;autoIncrement;not null" json:"userGroupID"`
-	UserID      uint64 `gorm:"foreignKey;not null" json:"userID"`
-	GroupID     uint64 `gorm:"foreignKey;not null" json:"groupID"`
+	UserGroupID uint64    `gorm:"primaryKey;autoIncrement;not null;index:idx_user_group_member,priority:2" json:"userGroupID"`
+	UserID      uint64    `gorm:"foreignKey;not null;uniqueIndex:idx_user_group,priority:2" json:"userID"`
+	GroupID     uint64    `gorm:"foreignKey;not null;index:idx_user_group_member,priority:1;uniqueIndex:idx_user_group,priority:1" json:"groupID"`
+	Role        GroupRole `gorm:"type:varchar(20);not null;default:'member'" json:"role"`
+	InviteID    uint64    `gorm:"index" json:"inviteID,omitempty"`
+	// CommunityID is set when joining a community added the member, so
+	// leaving it only undoes what it did.
+	CommunityID uint64 `gorm:"index" json:"communityID,omitempty"`
+	GroupPermissions
+	ReadOnlyUntil *time.Time `json:"readOnlyUntil,omitempty"`
+	NoMediaUntil  *time.Time `json:"noMediaUntil,omitempty"`
+	// RulesAcceptedAt is when the member accepted the group rules, nil
+	// until they do.
+	RulesAcceptedAt *time.Time `json:"rulesAcceptedAt,omitempty"`
 }
 
 type UserGroupInterface struct {
-	ID      *uint64
-	UserID  *uint64
-	GroupID *uint64
+	ID       *uint64
+	UserID   *uint64
+	GroupID  *uint64
+	Role     *GroupRole
+	InviteID *uint64
+}
+
+// GroupMemberQuery pages through a group's members in join order, starting
+// after the membership After. Search matches name or username.
+type GroupMemberQuery struct {
+	GroupID uint64
+	Search  string
+	Role    *GroupRole
+	After   uint64
+	Limit   int
+}
+
+// GroupMemberProfile is a membership joined with the member's profile.
+type GroupMemberProfile struct {
+	UserGroupID    uint64    `json:"-"`
+	UserID         uint64    `json:"userID"`
+	Name           string    `json:"name"`
+	Username       string    `json:"username"`
+	ProfilePicture string    `json:"profilePicture"`
+	IsActive       string    `json:"isActive"`
+	LastSeen       time.Time `json:"lastSeen"`
+	Role           GroupRole `json:"role"`
+	GroupPermissions
+	JoinedAt time.Time `json:"joinedAt"`
 }
 
 type UserGroupDTO struct {
@@ -24,9 +99,95 @@ type UserGroupDTO struct {
 }
 
 type UserGroupRepository interface {
+	// Create adds the membership; adding someone who is already a member
+	// does nothing.
 	Create(ctx context.Context, userGroup UserGroup) error
 	Get(ctx context.Context, ugi UserGroupInterface) ([]UserGroup, error)
 	Update(ctx context.Context, userGroup UserGroup) error
 	Delete(ctx context.Context, ugi UserGroupInterface) error
 	GetGroupWithUserGroups(ctx context.Context, groupID uint64) ([]GroupDTO, []UserGroupDTO, error)
+	// SetRole writes the role and every permission flag of the membership,
+	// including flags being cleared.
+	SetRole(ctx context.Context, userGroup UserGroup) error
+	// TransferOwnership writes the roles of the former and the new owner and
+	// makes the new owner the group's creator, in one transaction.
+	TransferOwnership(ctx context.Context, formerOwner, newOwner UserGroup) error
+	// SetRestrictions writes both temporary restrictions, clearing those
+	// that are nil.
+	SetRestrictions(ctx context.Context, userGroup UserGroup) error
+	AcceptRules(ctx context.Context, groupID, userID uint64, at time.Time) error
+	Members(ctx context.Context, q GroupMemberQuery) ([]GroupMemberProfile, error)
+	Count(ctx context.Context, groupID uint64) (int64, error)
+}
+
+func (r GroupRole) rank() int {
+	switch r {
+	case GroupOwner:
+		return 3
+	case GroupAdmin:
+		return 2
+	case GroupMember:
+		return 1
+	}
+
+	return 0
+}
+
+// Can reports whether the member's role, and for admins their permission
+// flags, allow action. The owner can do everything.
+func (ug UserGroup) Can(action GroupAction) bool {
+	switch ug.Role {
+	case GroupOwner:
+		return true
+	case GroupAdmin:
+		switch action {
+		case GroupRead, GroupSendMessages:
+			return true
+		case GroupAddMembers:
+			return ug.CanAddMembers
+		case GroupRemoveMembers:
+			return ug.CanRemoveMembers
+		case GroupDeleteMessages:
+			return ug.CanDeleteMessages
+		case GroupPinMessages:
+			return ug.CanPinMessages
+		case GroupEditInfo:
+			return ug.CanEditInfo
+		case GroupManageTopics:
+			return ug.CanManageTopics
+		}
+	case GroupMember:
+		return action == GroupRead || action == GroupSendMessages
+	case GroupRestricted:
+		return action == GroupRead
+	}
+
+	return false
+}
+
+// ReadOnly reports whether a temporary restriction stops the member from
+// sending messages at now.
+func (ug UserGroup) ReadOnly(now time.Time) bool {
+	return ug.ReadOnlyUntil != nil && now.Before(*ug.ReadOnlyUntil)
+}
+
+// MediaBlocked reports whether the member may only send text at now.
+func (ug UserGroup) MediaBlocked(now time.Time) bool {
+	return ug.NoMediaUntil != nil && now.Before(*ug.NoMediaUntil)
+}
+
+// MustAcceptRules reports whether the member has to accept the rules of
+// group before posting. Admins and the owner never do.
+func (ug UserGroup) MustAcceptRules(group Group) bool {
+	if !group.RulesRequired || ug.RulesAcceptedAt != nil {
+		return false
+	}
+
+	return ug.Role != GroupOwner && ug.Role != GroupAdmin
+}
+
+// Outranks reports whether ug sits above other in the group, which is what
+// it takes to remove or restrict them.
+func (ug UserGroup) Outranks(other UserGroup) bool {
+	return ug.Role.rank() > other.Role.rank()
 }
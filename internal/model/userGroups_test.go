@@ -0,0 +1,123 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+var allGroupActions = []GroupAction{
+	GroupRead, GroupSendMessages, GroupAddMembers, GroupRemoveMembers, GroupDeleteMessages,
+	GroupPinMessages, GroupEditInfo, GroupManageTopics, GroupManageAdmins, GroupDelete, GroupTransfer,
+}
+
+func TestUserGroupCan(t *testing.T) {
+	allAdmin := GroupPermissions{
+		CanAddMembers:     true,
+		CanRemoveMembers:  true,
+		CanDeleteMessages: true,
+		CanPinMessages:    true,
+		CanEditInfo:       true,
+		CanManageTopics:   true,
+	}
+
+	tests := []struct {
+		name    string
+		member  UserGroup
+		allowed []GroupAction
+	}{
+		{
+			name:    "owner",
+			member:  UserGroup{Role: GroupOwner},
+			allowed: allGroupActions,
+		},
+		{
+			name:    "admin without permissions",
+			member:  UserGroup{Role: GroupAdmin},
+			allowed: []GroupAction{GroupRead, GroupSendMessages},
+		},
+		{
+			name:   "admin with every permission",
+			member: UserGroup{Role: GroupAdmin, GroupPermissions: allAdmin},
+			allowed: []GroupAction{GroupRead, GroupSendMessages, GroupAddMembers, GroupRemoveMembers,
+				GroupDeleteMessages, GroupPinMessages, GroupEditInfo, GroupManageTopics},
+		},
+		{
+			name:    "admin who may pin",
+			member:  UserGroup{Role: GroupAdmin, GroupPermissions: GroupPermissions{CanPinMessages: true}},
+			allowed: []GroupAction{GroupRead, GroupSendMessages, GroupPinMessages},
+		},
+		{
+			name:    "member",
+			member:  UserGroup{Role: GroupMember},
+			allowed: []GroupAction{GroupRead, GroupSendMessages},
+		},
+		{
+			name:    "member with stray permissions",
+			member:  UserGroup{Role: GroupMember, GroupPermissions: allAdmin},
+			allowed: []GroupAction{GroupRead, GroupSendMessages},
+		},
+		{
+			name:    "restricted",
+			member:  UserGroup{Role: GroupRestricted},
+			allowed: []GroupAction{GroupRead},
+		},
+		{
+			name:   "unknown role",
+			member: UserGroup{Role: "guest"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed := make(map[GroupAction]bool, len(tt.allowed))
+			for _, action := range tt.allowed {
+				allowed[action] = true
+			}
+
+			for _, action := range allGroupActions {
+				if got := tt.member.Can(action); got != allowed[action] {
+					t.Errorf("Can(%s) = %v, want %v", action, got, allowed[action])
+				}
+			}
+		})
+	}
+}
+
+func TestUserGroupOutranks(t *testing.T) {
+	roles := []GroupRole{GroupRestricted, GroupMember, GroupAdmin, GroupOwner}
+
+	for i, a := range roles {
+		for j, b := range roles {
+			got := UserGroup{Role: a}.Outranks(UserGroup{Role: b})
+			if want := i > j; got != want {
+				t.Errorf("%s outranks %s = %v, want %v", a, b, got, want)
+			}
+		}
+	}
+}
+
+func TestUserGroupMustAcceptRules(t *testing.T) {
+	accepted := time.Now()
+
+	tests := []struct {
+		name   string
+		member UserGroup
+		group  Group
+		want   bool
+	}{
+		{name: "rules not required", member: UserGroup{Role: GroupMember}, group: Group{}},
+		{name: "member yet to accept", member: UserGroup{Role: GroupMember}, group: Group{RulesRequired: true}, want: true},
+		{name: "restricted yet to accept", member: UserGroup{Role: GroupRestricted}, group: Group{RulesRequired: true}, want: true},
+		{name: "member accepted", member: UserGroup{Role: GroupMember, RulesAcceptedAt: &accepted}, group: Group{RulesRequired: true}},
+		{name: "admin", member: UserGroup{Role: GroupAdmin}, group: Group{RulesRequired: true}},
+		{name: "owner", member: UserGroup{Role: GroupOwner}, group: Group{RulesRequired: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.member.MustAcceptRules(tt.group); got != tt.want {
+				t.Fatalf("MustAcceptRules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
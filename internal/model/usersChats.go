@@ -5,9 +5,10 @@ import (
 )
 
 type Chat struct {
-	ChatID     uint64 `gorm:"primaryKey;autoIncrement;not null" json:"chatID"`
-	UserID     uint64 `gorm:"foreignKey;not null" json:"userID"`
-	ReceiverID uint64 `gorm:"foreignKey;not null" json:"receiverID"`
+	ChatID         uint64 `gorm:"primaryKey;autoIncrement;not null" json:"chatID"`
+	UserID         uint64 `gorm:"foreignKey;not null" json:"userID"`
+	ReceiverID     uint64 `gorm:"foreignKey;not null" json:"receiverID"`
+	ConversationID uint64 `gorm:"uniqueIndex" json:"conversationID"`
 }
 
 type ChatInterface struct {
@@ -20,5 +21,20 @@ type UserChatRepository interface {
 	Create(ctx context.Context, userChat Chat) error
 	Get(ctx context.Context, ci ChatInterface) ([]Chat, error)
 	Update(ctx context.Context, userChat Chat) error
+	// Delete removes the matching chats together with their conversations,
+	// messages and the per-user settings and folder entries on them.
 	Delete(ctx context.Context, ci ChatInterface) error
+	// Open returns the private chat between the two users regardless of who
+	// started it, creating it when none exists. created reports whether a new
+	// chat was made.
+	Open(ctx context.Context, userID, peerID uint64) (chat Chat, created bool, err error)
+}
+
+// Peer returns the other participant of the chat for userID.
+func (c Chat) Peer(userID uint64) uint64 {
+	if c.UserID == userID {
+		return c.ReceiverID
+	}
+
+	return c.UserID
 }
@@ -0,0 +1,117 @@
+package bookmarkRepoImpl
+
+import (
+	"backend/internal/model"
+	"backend/utils"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func ToBookmarkDTO(bookmark model.Bookmark) *model.BookmarkDTO {
+	if len(bookmark.Payload) == 0 {
+		bookmark.Payload = nil
+	}
+
+	return &model.BookmarkDTO{
+		Bookmark:  bookmark,
+		CreatedAt: time.Now(),
+	}
+}
+
+func (b *Repository) Create(ctx context.Context, bookmark model.Bookmark) (uint64, error) {
+	bookmarkDTO := ToBookmarkDTO(bookmark)
+
+	result := b.db.WithContext(ctx).Create(bookmarkDTO)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return bookmarkDTO.BookmarkID, nil
+}
+
+func (b *Repository) Get(ctx context.Context, bi model.BookmarkInterface) ([]model.BookmarkDTO, error) {
+	var bookmarkDTOs []model.BookmarkDTO
+	var condition model.BookmarkDTO
+
+	if bi.ID != nil {
+		condition.BookmarkID = *bi.ID
+	}
+	if bi.UserID != nil {
+		condition.UserID = *bi.UserID
+	}
+	if bi.MessageID != nil {
+		condition.MessageID = *bi.MessageID
+	}
+	if bi.Kind != nil {
+		condition.Kind = *bi.Kind
+	}
+
+	query := b.db.WithContext(ctx).Where(&condition)
+	if bi.Tag != nil {
+		query = query.Where("(',' || tags || ',') LIKE ?", "%,"+utils.EscapeLike(*bi.Tag)+",%")
+	}
+	if bi.Query != nil {
+		pattern := "%" + utils.EscapeLike(*bi.Query) + "%"
+		query = query.Where("(content ILIKE ? OR note ILIKE ?)", pattern, pattern)
+	}
+
+	result := query.Order("created_at DESC").Find(&bookmarkDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return bookmarkDTOs, nil
+}
+
+func (b *Repository) Update(ctx context.Context, bookmark model.Bookmark) error {
+	var condition model.BookmarkDTO
+	condition.BookmarkID = bookmark.BookmarkID
+	condition.UserID = bookmark.UserID
+
+	dto := model.BookmarkDTO{
+		Bookmark:  bookmark,
+		UpdatedAt: time.Now(),
+	}
+
+	result := b.db.WithContext(ctx).Model(&model.BookmarkDTO{}).Where(&condition).
+		Select("note", "tags", "content", "updated_at").
+		Updates(&dto)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (b *Repository) Delete(ctx context.Context, bi model.BookmarkInterface) error {
+	var condition model.BookmarkDTO
+
+	if bi.ID != nil {
+		condition.BookmarkID = *bi.ID
+	}
+	if bi.UserID != nil {
+		condition.UserID = *bi.UserID
+	}
+	if bi.MessageID != nil {
+		condition.MessageID = *bi.MessageID
+	}
+
+	result := b.db.WithContext(ctx).Where(&condition).Delete(&model.BookmarkDTO{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package channelRepoImpl
+
+import (
+	"backend/internal/model"
+	"backend/internal/repositoryImpl/conversationRepoImpl"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func ToChannelDTO(channel model.Channel) *model.ChannelDTO {
+	return &model.ChannelDTO{
+		Channel: model.Channel{
+			ChannelID:      channel.ChannelID,
+			Name:           channel.Name,
+			Description:    channel.Description,
+			Handle:         channel.Handle,
+			Owner:          channel.Owner,
+			ConversationID: channel.ConversationID,
+		},
+		CreatedAt: time.Now(),
+	}
+}
+
+func (r *Repository) Create(ctx context.Context, channel model.Channel) (uint64, error) {
+	channelDTO := ToChannelDTO(channel)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(channelDTO).Error; err != nil {
+			return err
+		}
+
+		conversationID, err := conversationRepoImpl.Resolve(tx, model.TypeCH, channelDTO.ChannelID)
+		if err != nil {
+			return err
+		}
+		channelDTO.ConversationID = conversationID
+
+		if err = tx.Model(channelDTO).UpdateColumn("conversation_id", conversationID).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&model.UserChannelDTO{
+			UserChannel: model.UserChannel{
+				ChannelID: channelDTO.ChannelID,
+				UserID:    channelDTO.Owner,
+				Role:      model.ChannelOwner,
+			},
+			CreatedAt: time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return channelDTO.ChannelID, nil
+}
+
+func (r *Repository) Get(ctx context.Context, ci model.ChannelInterface) ([]model.Channel, error) {
+	var channelDTOs []model.ChannelDTO
+	var condition model.ChannelDTO
+
+	if ci.ID != nil {
+		condition.ChannelID = *ci.ID
+	}
+	if ci.Handle != nil {
+		condition.Handle = *ci.Handle
+	}
+	if ci.OwnerID != nil {
+		condition.Owner = *ci.OwnerID
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Find(&channelDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	channels := make([]model.Channel, len(channelDTOs))
+	for i, channelDTO := range channelDTOs {
+		channels[i] = *channelDTO.ToChannel()
+	}
+
+	return channels, nil
+}
+
+func (r *Repository) Update(ctx context.Context, channel model.Channel) error {
+	var condition model.ChannelDTO
+	condition.ChannelID = channel.ChannelID
+
+	dto := model.ChannelDTO{
+		Channel:   channel,
+		UpdatedAt: time.Now(),
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Updates(&dto)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) Delete(ctx context.Context, ci model.ChannelInterface) error {
+	var condition model.ChannelDTO
+
+	if ci.ID != nil {
+		condition.ChannelID = *ci.ID
+	}
+	if ci.Handle != nil {
+		condition.Handle = *ci.Handle
+	}
+	if ci.OwnerID != nil {
+		condition.Owner = *ci.OwnerID
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var channels []model.ChannelDTO
+		if err := tx.Where(&condition).Find(&channels).Error; err != nil {
+			return err
+		}
+		if len(channels) == 0 {
+			return nil
+		}
+
+		channelIDs := make([]uint64, len(channels))
+		conversationIDs := make([]uint64, len(channels))
+		for i, channel := range channels {
+			channelIDs[i] = channel.ChannelID
+			conversationIDs[i] = channel.ConversationID
+		}
+
+		if err := tx.Where("channel_id IN ?", channelIDs).Delete(&model.UserChannelDTO{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("channel_id IN ?", channelIDs).Delete(&model.ChannelDTO{}).Error; err != nil {
+			return err
+		}
+
+		return conversationRepoImpl.Delete(tx, model.TypeCH, channelIDs, conversationIDs)
+	})
+}
@@ -0,0 +1,71 @@
+package channelViewRepoImpl
+
+import (
+	"backend/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Record(ctx context.Context, userID uint64, messageIDs []uint64) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	views := make([]model.ChannelViewDTO, len(messageIDs))
+	for i, messageID := range messageIDs {
+		views[i] = model.ChannelViewDTO{
+			ChannelView: model.ChannelView{
+				MessageID: messageID,
+				UserID:    userID,
+			},
+			CreatedAt: time.Now(),
+		}
+	}
+
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&views)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) Count(ctx context.Context, messageIDs []uint64) (map[uint64]int64, error) {
+	counts := make(map[uint64]int64, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		MessageID uint64
+		Views     int64
+	}
+
+	result := r.db.WithContext(ctx).Model(&model.ChannelViewDTO{}).
+		Select("message_id, COUNT(*) AS views").
+		Where("message_id IN ?", messageIDs).
+		Group("message_id").
+		Find(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	for _, row := range rows {
+		counts[row.MessageID] = row.Views
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,269 @@
+package communityRepoImpl
+
+import (
+	"backend/internal/model"
+	"backend/internal/repositoryImpl/groupRepoImpl"
+	"backend/internal/repositoryImpl/userGroupRepoImpl"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Create(ctx context.Context, community model.Community, announcement model.Group) (uint64, uint64, error) {
+	communityDTO := &model.CommunityDTO{
+		Community: community,
+		CreatedAt: time.Now(),
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		groupID, err := groupRepoImpl.New(tx).Create(ctx, announcement)
+		if err != nil {
+			return err
+		}
+
+		if err = userGroupRepoImpl.New(tx).Create(ctx, model.UserGroup{
+			GroupID: groupID,
+			UserID:  community.Owner,
+			Role:    model.GroupOwner,
+		}); err != nil {
+			return err
+		}
+
+		communityDTO.AnnouncementGroupID = groupID
+		if err = tx.Create(communityDTO).Error; err != nil {
+			return err
+		}
+
+		return New(tx).AddMember(ctx, model.UserCommunity{
+			CommunityID: communityDTO.CommunityID,
+			UserID:      community.Owner,
+			Role:        model.CommunityOwner,
+		})
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return communityDTO.CommunityID, communityDTO.AnnouncementGroupID, nil
+}
+
+func (r *Repository) Get(ctx context.Context, ci model.CommunityInterface) ([]model.Community, error) {
+	var communityDTOs []model.CommunityDTO
+	var condition model.CommunityDTO
+
+	if ci.ID != nil {
+		condition.CommunityID = *ci.ID
+	}
+	if ci.OwnerID != nil {
+		condition.Owner = *ci.OwnerID
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Order("community_id").Find(&communityDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	communities := make([]model.Community, len(communityDTOs))
+	for i, communityDTO := range communityDTOs {
+		communities[i] = communityDTO.Community
+	}
+
+	return communities, nil
+}
+
+func (r *Repository) GetForUser(ctx context.Context, userID uint64) ([]model.Community, error) {
+	var communityDTOs []model.CommunityDTO
+
+	result := r.db.WithContext(ctx).
+		Joins("JOIN user_community_dtos uc ON uc.community_id = community_dtos.community_id").
+		Where("uc.user_id = ?", userID).
+		Order("community_dtos.community_id").
+		Find(&communityDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	communities := make([]model.Community, len(communityDTOs))
+	for i, communityDTO := range communityDTOs {
+		communities[i] = communityDTO.Community
+	}
+
+	return communities, nil
+}
+
+func (r *Repository) Update(ctx context.Context, community model.Community) error {
+	dto := model.CommunityDTO{
+		Community: community,
+		UpdatedAt: time.Now(),
+	}
+
+	result := r.db.WithContext(ctx).Model(&model.CommunityDTO{}).
+		Where("community_id = ?", community.CommunityID).
+		Select("name", "description", "owner", "updated_at").
+		Updates(&dto)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) Delete(ctx context.Context, communityID uint64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var communities []model.CommunityDTO
+		if err := tx.Where("community_id = ?", communityID).Find(&communities).Error; err != nil {
+			return err
+		}
+		if len(communities) == 0 {
+			return nil
+		}
+
+		if err := groupRepoImpl.New(tx).Delete(ctx, model.GroupInterface{
+			ID: &communities[0].AnnouncementGroupID,
+		}); err != nil {
+			return err
+		}
+		if err := tx.Where("community_id = ?", communityID).Delete(&model.CommunityLinkDTO{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("community_id = ?", communityID).Delete(&model.UserCommunityDTO{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("community_id = ?", communityID).Delete(&model.CommunityDTO{}).Error
+	})
+}
+
+func (r *Repository) AddMember(ctx context.Context, member model.UserCommunity) error {
+	member.UserCommunityID = 0
+	dto := model.UserCommunityDTO{
+		UserCommunity: member,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&dto)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) GetMembers(ctx context.Context, uci model.UserCommunityInterface) ([]model.UserCommunityDTO, error) {
+	var memberDTOs []model.UserCommunityDTO
+	var condition model.UserCommunityDTO
+
+	if uci.CommunityID != nil {
+		condition.CommunityID = *uci.CommunityID
+	}
+	if uci.UserID != nil {
+		condition.UserID = *uci.UserID
+	}
+	if uci.Role != nil {
+		condition.Role = *uci.Role
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Order("user_community_id").Find(&memberDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return memberDTOs, nil
+}
+
+func (r *Repository) CountMembers(ctx context.Context, communityID uint64) (int64, error) {
+	var count int64
+
+	result := r.db.WithContext(ctx).Model(&model.UserCommunityDTO{}).Where("community_id = ?", communityID).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return count, nil
+}
+
+func (r *Repository) SetMemberRole(ctx context.Context, communityID, userID uint64, role model.CommunityRole) error {
+	result := r.db.WithContext(ctx).Model(&model.UserCommunityDTO{}).
+		Where("community_id = ? AND user_id = ?", communityID, userID).
+		Updates(map[string]interface{}{"role": role, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) RemoveMember(ctx context.Context, communityID, userID uint64) error {
+	result := r.db.WithContext(ctx).Where("community_id = ? AND user_id = ?", communityID, userID).
+		Delete(&model.UserCommunityDTO{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) Link(ctx context.Context, link model.CommunityLink) error {
+	link.LinkID = 0
+	dto := model.CommunityLinkDTO{
+		CommunityLink: link,
+		CreatedAt:     time.Now(),
+	}
+
+	result := r.db.WithContext(ctx).Create(&dto)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) GetLinks(ctx context.Context, cli model.CommunityLinkInterface) ([]model.CommunityLink, error) {
+	var linkDTOs []model.CommunityLinkDTO
+	var condition model.CommunityLinkDTO
+
+	if cli.CommunityID != nil {
+		condition.CommunityID = *cli.CommunityID
+	}
+	if cli.Type != nil {
+		condition.Type = *cli.Type
+	}
+	if cli.ChatID != nil {
+		condition.ChatID = *cli.ChatID
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Order("link_id").Find(&linkDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	links := make([]model.CommunityLink, len(linkDTOs))
+	for i, linkDTO := range linkDTOs {
+		links[i] = linkDTO.CommunityLink
+	}
+
+	return links, nil
+}
+
+func (r *Repository) Unlink(ctx context.Context, communityID uint64, chatType model.MessageType, chatID uint64) error {
+	result := r.db.WithContext(ctx).Where("community_id = ? AND type = ? AND chat_id = ?", communityID, chatType, chatID).
+		Delete(&model.CommunityLinkDTO{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
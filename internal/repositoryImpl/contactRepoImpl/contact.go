@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
@@ -48,7 +49,10 @@ func ToContactDTO(contact model.Contact) *ContactDTO {
 func (c *Repository) Create(ctx context.Context, contact model.Contact) error {
 	contactDTO := ToContactDTO(contact)
 
-	result := c.db.WithContext(ctx).Create(contactDTO)
+	result := c.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "contact_user_id"}},
+		DoNothing: true,
+	}).Create(contactDTO)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -66,11 +70,14 @@ func (c *Repository) Get(ctx context.Context, ci model.ContactInterface) ([]mode
 	if ci.UserID != nil {
 		condition.UserID = *ci.UserID
 	}
+	if ci.ContactUserID != nil {
+		condition.ContactUserID = *ci.ContactUserID
+	}
 	if ci.Status != nil {
 		condition.Status = *ci.Status
 	}
 
-	result := c.db.WithContext(ctx).Where(&condition).Find(&contactDTOs)
+	result := c.db.WithContext(ctx).Where(&condition).Order("contact_id").Find(&contactDTOs)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -111,7 +118,7 @@ func (c *Repository) Delete(ctx context.Context, ci model.ContactInterface) erro
 	if ci.UserID != nil {
 		condition.UserID = *ci.UserID
 	}
-	if ci.UserID != nil {
+	if ci.ContactUserID != nil {
 		condition.ContactUserID = *ci.ContactUserID
 	}
 	if ci.Status != nil {
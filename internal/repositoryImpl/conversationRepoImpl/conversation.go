@@ -0,0 +1,145 @@
+package conversationRepoImpl
+
+import (
+	"backend/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// Resolve is the get-or-create used by the repositories that create chats,
+// groups and channels, inside the transaction that inserts the row.
+func Resolve(db *gorm.DB, chatType model.MessageType, chatID uint64) (uint64, error) {
+	dto := model.ConversationDTO{
+		Conversation: model.Conversation{
+			Type:   chatType,
+			ChatID: chatID,
+		},
+		CreatedAt: time.Now(),
+	}
+
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "type"}, {Name: "chat_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"type": chatType}),
+	}).Create(&dto)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if dto.ConversationID == 0 {
+		var existing model.ConversationDTO
+		result = db.Where("type = ? AND chat_id = ?", chatType, chatID).First(&existing)
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		return existing.ConversationID, nil
+	}
+
+	return dto.ConversationID, nil
+}
+
+// owners names the table and key column of each kind of conversation.
+var owners = map[model.MessageType]struct{ table, key string }{
+	model.TypePV: {table: "user_chat_dtos", key: "chat_id"},
+	model.TypeGP: {table: "group_dtos", key: "group_id"},
+	model.TypeCH: {table: "channel_dtos", key: "channel_id"},
+}
+
+// Lookup reads the conversation of an existing chat, group or channel and
+// locks that row against deletion until db's transaction ends, so rows
+// written under the conversation can not outlive it.
+func Lookup(db *gorm.DB, chatType model.MessageType, chatID uint64) (uint64, error) {
+	owner, ok := owners[chatType]
+	if !ok {
+		return 0, model.ErrConversationNotFound
+	}
+
+	var conversationIDs []uint64
+	result := db.Table(owner.table).
+		Where(owner.key+" = ? AND conversation_id IS NOT NULL", chatID).
+		Clauses(clause.Locking{Strength: "SHARE"}).
+		Pluck("conversation_id", &conversationIDs)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if len(conversationIDs) == 0 {
+		return 0, model.ErrConversationNotFound
+	}
+
+	return conversationIDs[0], nil
+}
+
+// Delete removes the conversations of chats, groups or channels of one type
+// together with their messages, the channel views on those messages and the
+// per-user settings, folder entries and community links pointing at them. It
+// is called inside the transaction that deletes the chats, groups or channels
+// themselves, after their rows are gone.
+func Delete(db *gorm.DB, chatType model.MessageType, chatIDs, conversationIDs []uint64) error {
+	if len(chatIDs) == 0 {
+		return nil
+	}
+
+	messages := db.Model(&model.MessageDTO{}).Select("message_id").
+		Where("conversation_id IN ?", conversationIDs)
+	if err := db.Where("message_id IN (?)", messages).Delete(&model.ChannelViewDTO{}).Error; err != nil {
+		return err
+	}
+	if err := db.Where("conversation_id IN ?", conversationIDs).Delete(&model.MessageDTO{}).Error; err != nil {
+		return err
+	}
+
+	for _, dto := range []interface{}{
+		&model.ConversationSettingDTO{},
+		&model.FolderEntryDTO{},
+		&model.CommunityLinkDTO{},
+	} {
+		if err := db.Where("type = ? AND chat_id IN ?", chatType, chatIDs).Delete(dto).Error; err != nil {
+			return err
+		}
+	}
+
+	return db.Where("conversation_id IN ?", conversationIDs).Delete(&model.ConversationDTO{}).Error
+}
+
+func (r *Repository) Lookup(ctx context.Context, chatType model.MessageType, chatID uint64) (uint64, error) {
+	return Lookup(r.db.WithContext(ctx), chatType, chatID)
+}
+
+func (r *Repository) Get(ctx context.Context, ci model.ConversationInterface) ([]model.Conversation, error) {
+	var conversationDTOs []model.ConversationDTO
+	var condition model.ConversationDTO
+
+	if ci.ID != nil {
+		condition.ConversationID = *ci.ID
+	}
+	if ci.Type != nil {
+		condition.Type = *ci.Type
+	}
+	if ci.ChatID != nil {
+		condition.ChatID = *ci.ChatID
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Find(&conversationDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	conversations := make([]model.Conversation, len(conversationDTOs))
+	for i, conversationDTO := range conversationDTOs {
+		conversations[i] = *conversationDTO.ToConversation()
+	}
+
+	return conversations, nil
+}
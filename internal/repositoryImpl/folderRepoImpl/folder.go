@@ -0,0 +1,168 @@
+package folderRepoImpl
+
+import (
+	"backend/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (f *Repository) Create(ctx context.Context, folder model.Folder, entries []model.FolderEntry) (uint64, error) {
+	folderDTO := &model.FolderDTO{
+		Folder:    folder,
+		CreatedAt: time.Now(),
+	}
+
+	err := f.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(folderDTO).Error; err != nil {
+			return err
+		}
+
+		return setEntries(tx, folderDTO.FolderID, entries)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return folderDTO.FolderID, nil
+}
+
+func (f *Repository) Get(ctx context.Context, fi model.FolderInterface) ([]model.Folder, error) {
+	var folderDTOs []model.FolderDTO
+	var condition model.FolderDTO
+
+	if fi.ID != nil {
+		condition.FolderID = *fi.ID
+	}
+	if fi.UserID != nil {
+		condition.UserID = *fi.UserID
+	}
+
+	result := f.db.WithContext(ctx).Where(&condition).Order("position, folder_id").Find(&folderDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	folders := make([]model.Folder, len(folderDTOs))
+	for i, folderDTO := range folderDTOs {
+		folders[i] = folderDTO.Folder
+	}
+
+	return folders, nil
+}
+
+// Update writes every column of folder, so callers pass the full row and
+// boolean rules can be switched off.
+func (f *Repository) Update(ctx context.Context, folder model.Folder) error {
+	return update(f.db.WithContext(ctx), folder)
+}
+
+func (f *Repository) Replace(ctx context.Context, folder model.Folder, entries []model.FolderEntry) error {
+	return f.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := update(tx, folder); err != nil {
+			return err
+		}
+
+		return setEntries(tx, folder.FolderID, entries)
+	})
+}
+
+func update(db *gorm.DB, folder model.Folder) error {
+	var condition model.FolderDTO
+	condition.FolderID = folder.FolderID
+	condition.UserID = folder.UserID
+
+	dto := model.FolderDTO{
+		Folder:    folder,
+		UpdatedAt: time.Now(),
+	}
+
+	result := db.Model(&model.FolderDTO{}).Where(&condition).
+		Select("name", "position", "unread_only", "groups_only", "contacts_only", "exclude_muted", "updated_at").
+		Updates(&dto)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (f *Repository) Delete(ctx context.Context, fi model.FolderInterface) error {
+	var condition model.FolderDTO
+
+	if fi.ID != nil {
+		condition.FolderID = *fi.ID
+	}
+	if fi.UserID != nil {
+		condition.UserID = *fi.UserID
+	}
+
+	return f.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uint64
+		if err := tx.Model(&model.FolderDTO{}).Where(&condition).Pluck("folder_id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := tx.Where("folder_id IN ?", ids).Delete(&model.FolderEntryDTO{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("folder_id IN ?", ids).Delete(&model.FolderDTO{}).Error
+	})
+}
+
+func (f *Repository) GetEntries(ctx context.Context, folderIDs []uint64) ([]model.FolderEntry, error) {
+	if len(folderIDs) == 0 {
+		return nil, nil
+	}
+
+	var entryDTOs []model.FolderEntryDTO
+
+	result := f.db.WithContext(ctx).Where("folder_id IN ?", folderIDs).Find(&entryDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	entries := make([]model.FolderEntry, len(entryDTOs))
+	for i, entryDTO := range entryDTOs {
+		entries[i] = entryDTO.FolderEntry
+	}
+
+	return entries, nil
+}
+
+// setEntries replaces the include/exclude lists of a folder within tx.
+func setEntries(tx *gorm.DB, folderID uint64, entries []model.FolderEntry) error {
+	if err := tx.Where("folder_id = ?", folderID).Delete(&model.FolderEntryDTO{}).Error; err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	entryDTOs := make([]model.FolderEntryDTO, len(entries))
+	for i, entry := range entries {
+		entry.FolderEntryID = 0
+		entry.FolderID = folderID
+		entryDTOs[i] = model.FolderEntryDTO{
+			FolderEntry: entry,
+			CreatedAt:   time.Now(),
+		}
+	}
+
+	return tx.Create(&entryDTOs).Error
+}
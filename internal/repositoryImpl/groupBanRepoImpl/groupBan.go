@@ -0,0 +1,76 @@
+package groupBanRepoImpl
+
+import (
+	"backend/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Save(ctx context.Context, ban model.GroupBan) error {
+	ban.BanID = 0
+	dto := model.GroupBanDTO{
+		GroupBan:  ban,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"banned_by", "reason", "expires_at", "updated_at"}),
+	}).Create(&dto)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) Get(ctx context.Context, gbi model.GroupBanInterface) ([]model.GroupBanDTO, error) {
+	var banDTOs []model.GroupBanDTO
+	var condition model.GroupBanDTO
+
+	if gbi.GroupID != nil {
+		condition.GroupID = *gbi.GroupID
+	}
+	if gbi.UserID != nil {
+		condition.UserID = *gbi.UserID
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Order("ban_id").Find(&banDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return banDTOs, nil
+}
+
+func (r *Repository) Delete(ctx context.Context, gbi model.GroupBanInterface) error {
+	var condition model.GroupBanDTO
+
+	if gbi.GroupID != nil {
+		condition.GroupID = *gbi.GroupID
+	}
+	if gbi.UserID != nil {
+		condition.UserID = *gbi.UserID
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Delete(&model.GroupBanDTO{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package groupInviteRepoImpl
+
+import (
+	"backend/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func ToGroupInviteDTO(invite model.GroupInvite) *model.GroupInviteDTO {
+	return &model.GroupInviteDTO{
+		GroupInvite: invite,
+		CreatedAt:   time.Now(),
+	}
+}
+
+func (r *Repository) Create(ctx context.Context, invite model.GroupInvite) (uint64, error) {
+	inviteDTO := ToGroupInviteDTO(invite)
+
+	result := r.db.WithContext(ctx).Create(inviteDTO)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return inviteDTO.InviteID, nil
+}
+
+func (r *Repository) Get(ctx context.Context, gii model.GroupInviteInterface) ([]model.GroupInviteDTO, error) {
+	var inviteDTOs []model.GroupInviteDTO
+	var condition model.GroupInviteDTO
+
+	if gii.ID != nil {
+		condition.InviteID = *gii.ID
+	}
+	if gii.GroupID != nil {
+		condition.GroupID = *gii.GroupID
+	}
+	if gii.Token != nil {
+		condition.Token = *gii.Token
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Order("invite_id DESC").Find(&inviteDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return inviteDTOs, nil
+}
+
+func (r *Repository) Revoke(ctx context.Context, inviteID uint64) error {
+	result := r.db.WithContext(ctx).Model(&model.GroupInviteDTO{}).
+		Where("invite_id = ?", inviteID).
+		Updates(map[string]interface{}{"revoked": true, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// Use checks and increments in one statement so concurrent joins can not
+// push an invite past its MaxUses.
+func (r *Repository) Use(ctx context.Context, inviteID uint64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.GroupInviteDTO{}).
+		Where("invite_id = ? AND revoked = false AND (max_uses = 0 OR uses < max_uses) AND (expires_at IS NULL OR expires_at > ?)",
+			inviteID, time.Now()).
+		Updates(map[string]interface{}{"uses": gorm.Expr("uses + 1"), "updated_at": time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,62 @@
+package groupProfileChangeRepoImpl
+
+import (
+	"backend/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Create(ctx context.Context, changes []model.GroupProfileChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	dtos := make([]model.GroupProfileChangeDTO, len(changes))
+	for i, change := range changes {
+		change.ChangeID = 0
+		dtos[i] = model.GroupProfileChangeDTO{
+			GroupProfileChange: change,
+			CreatedAt:          now,
+			UpdatedAt:          now,
+		}
+	}
+
+	result := r.db.WithContext(ctx).Create(&dtos)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) Get(ctx context.Context, gpci model.GroupProfileChangeInterface) ([]model.GroupProfileChangeDTO, error) {
+	var changeDTOs []model.GroupProfileChangeDTO
+	var condition model.GroupProfileChangeDTO
+
+	if gpci.GroupID != nil {
+		condition.GroupID = *gpci.GroupID
+	}
+	if gpci.Field != nil {
+		condition.Field = *gpci.Field
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Order("change_id DESC").Find(&changeDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return changeDTOs, nil
+}
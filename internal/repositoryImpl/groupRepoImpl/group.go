@@ -2,6 +2,8 @@ package groupRepoImpl
 
 import (
 	"backend/internal/model"
+	"backend/internal/repositoryImpl/conversationRepoImpl"
+	"backend/internal/repositoryImpl/userGroupRepoImpl"
 	"context"
 	"time"
 
@@ -21,10 +23,18 @@ func New(db *gorm.DB) *Repository {
 func ToGroupDTO(group model.Group) *model.GroupDTO {
 	return &model.GroupDTO{
 		Group: model.Group{
-			GroupID:     group.GroupID,
-			Name:        group.Name,
-			Description: group.Description,
-			Creator:     group.Creator,
+			GroupID:          group.GroupID,
+			Name:             group.Name,
+			Description:      group.Description,
+			Avatar:           group.Avatar,
+			Creator:          group.Creator,
+			ConversationID:   group.ConversationID,
+			RequiresApproval: group.RequiresApproval,
+			SlowModeSeconds:  group.SlowModeSeconds,
+			ForumMode:        group.ForumMode,
+			Rules:            group.Rules,
+			WelcomeMessage:   group.WelcomeMessage,
+			RulesRequired:    group.RulesRequired,
 		},
 		CreatedAt: time.Now(),
 	}
@@ -33,9 +43,21 @@ func ToGroupDTO(group model.Group) *model.GroupDTO {
 func (g *Repository) Create(ctx context.Context, group model.Group) (uint64, error) {
 	groupDTO := ToGroupDTO(group)
 
-	result := g.db.WithContext(ctx).Create(groupDTO)
-	if result.Error != nil {
-		return 0, result.Error
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(groupDTO).Error; err != nil {
+			return err
+		}
+
+		conversationID, err := conversationRepoImpl.Resolve(tx, model.TypeGP, groupDTO.GroupID)
+		if err != nil {
+			return err
+		}
+		groupDTO.ConversationID = conversationID
+
+		return tx.Model(groupDTO).UpdateColumn("conversation_id", conversationID).Error
+	})
+	if err != nil {
+		return 0, err
 	}
 
 	return groupDTO.GroupID, nil
@@ -95,10 +117,111 @@ func (g *Repository) Delete(ctx context.Context, gi model.GroupInterface) error
 		condition.Name = *gi.Name
 	}
 
-	result := g.db.WithContext(ctx).Where(&condition).Delete(&model.GroupDTO{})
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var groups []model.GroupDTO
+		if err := tx.Where(&condition).Find(&groups).Error; err != nil {
+			return err
+		}
+		if len(groups) == 0 {
+			return nil
+		}
+
+		groupIDs := make([]uint64, len(groups))
+		conversationIDs := make([]uint64, len(groups))
+		for i, group := range groups {
+			groupIDs[i] = group.GroupID
+			conversationIDs[i] = group.ConversationID
+		}
+
+		for _, dto := range []interface{}{
+			&userGroupRepoImpl.UserGroupDTO{},
+			&model.GroupInviteDTO{},
+			&model.JoinRequestDTO{},
+			&model.GroupBanDTO{},
+			&model.GroupTopicDTO{},
+			&model.GroupProfileChangeDTO{},
+		} {
+			if err := tx.Where("group_id IN ?", groupIDs).Delete(dto).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("group_id IN ?", groupIDs).Delete(&model.GroupDTO{}).Error; err != nil {
+			return err
+		}
+
+		return conversationRepoImpl.Delete(tx, model.TypeGP, groupIDs, conversationIDs)
+	})
+}
+
+func (g *Repository) SetRequiresApproval(ctx context.Context, groupID uint64, required bool) error {
+	result := g.db.WithContext(ctx).Model(&model.GroupDTO{}).
+		Where("group_id = ?", groupID).
+		Updates(map[string]interface{}{"requires_approval": required, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (g *Repository) SetSlowMode(ctx context.Context, groupID uint64, seconds int) error {
+	result := g.db.WithContext(ctx).Model(&model.GroupDTO{}).
+		Where("group_id = ?", groupID).
+		Updates(map[string]interface{}{"slow_mode_seconds": seconds, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (g *Repository) SetForumMode(ctx context.Context, groupID uint64, enabled bool) error {
+	result := g.db.WithContext(ctx).Model(&model.GroupDTO{}).
+		Where("group_id = ?", groupID).
+		Updates(map[string]interface{}{"forum_mode": enabled, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (g *Repository) UpdateProfile(ctx context.Context, group model.Group) error {
+	dto := model.GroupDTO{
+		Group:     group,
+		UpdatedAt: time.Now(),
+	}
+
+	result := g.db.WithContext(ctx).Model(&model.GroupDTO{}).
+		Where("group_id = ?", group.GroupID).
+		Select("name", "description", "avatar", "updated_at").
+		Updates(&dto)
 	if result.Error != nil {
 		return result.Error
 	}
 
 	return nil
 }
+
+func (g *Repository) SetRules(ctx context.Context, group model.Group, resetAcceptance bool) error {
+	dto := model.GroupDTO{
+		Group:     group,
+		UpdatedAt: time.Now(),
+	}
+
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.GroupDTO{}).
+			Where("group_id = ?", group.GroupID).
+			Select("rules", "welcome_message", "rules_required", "updated_at").
+			Updates(&dto).Error; err != nil {
+			return err
+		}
+		if !resetAcceptance {
+			return nil
+		}
+
+		return tx.Model(&userGroupRepoImpl.UserGroupDTO{}).
+			Where("group_id = ? AND rules_accepted_at IS NOT NULL", group.GroupID).
+			Updates(map[string]interface{}{"rules_accepted_at": nil, "updated_at": time.Now()}).Error
+	})
+}
@@ -0,0 +1,139 @@
+package groupTopicRepoImpl
+
+import (
+	"backend/internal/model"
+	"backend/internal/repositoryImpl/messageRepoImpl"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Create(ctx context.Context, topic model.GroupTopic) (uint64, error) {
+	topic.TopicID = 0
+	dto := model.GroupTopicDTO{
+		GroupTopic: topic,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	result := r.db.WithContext(ctx).Create(&dto)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return dto.TopicID, nil
+}
+
+func (r *Repository) Get(ctx context.Context, gti model.GroupTopicInterface) ([]model.GroupTopicDTO, error) {
+	var topicDTOs []model.GroupTopicDTO
+	var condition model.GroupTopicDTO
+
+	if gti.ID != nil {
+		condition.TopicID = *gti.ID
+	}
+	if gti.GroupID != nil {
+		condition.GroupID = *gti.GroupID
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Order("topic_id").Find(&topicDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return topicDTOs, nil
+}
+
+func (r *Repository) Update(ctx context.Context, topic model.GroupTopic) error {
+	dto := model.GroupTopicDTO{
+		GroupTopic: topic,
+		UpdatedAt:  time.Now(),
+	}
+
+	result := r.db.WithContext(ctx).Model(&model.GroupTopicDTO{}).
+		Where("topic_id = ?", topic.TopicID).
+		Select("title", "closed", "pinned_message_id", "updated_at").
+		Updates(&dto)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) Delete(ctx context.Context, gti model.GroupTopicInterface) error {
+	var condition model.GroupTopicDTO
+
+	if gti.ID != nil {
+		condition.TopicID = *gti.ID
+	}
+	if gti.GroupID != nil {
+		condition.GroupID = *gti.GroupID
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var topics []model.GroupTopicDTO
+		if err := tx.Where(&condition).Find(&topics).Error; err != nil {
+			return err
+		}
+
+		for _, topic := range topics {
+			conversation := tx.Model(&model.GroupDTO{}).Select("conversation_id").
+				Where("group_id = ?", topic.GroupID)
+			if err := tx.Where("conversation_id = (?) AND topic_id = ?", conversation, topic.TopicID).
+				Delete(&messageRepoImpl.MessageDTO{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&model.GroupTopicDTO{}, topic.TopicID).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+const listQuery = `
+SELECT t.*, COALESCE(lm.message_id, 0) AS last_message_id, COALESCE(un.unread, 0) AS unread,
+	COALESCE(lm.created_at, t.created_at) AS activity_at
+FROM group_topic_dtos t
+JOIN group_dtos g ON g.group_id = t.group_id
+LEFT JOIN LATERAL (
+	SELECT m.message_id, m.created_at
+	FROM message_dtos m
+	WHERE m.conversation_id = g.conversation_id AND m.topic_id = t.topic_id
+	ORDER BY m.message_id DESC
+	LIMIT 1
+) lm ON true
+LEFT JOIN LATERAL (
+	SELECT COUNT(*) AS unread
+	FROM message_dtos m
+	WHERE m.conversation_id = g.conversation_id AND m.topic_id = t.topic_id
+		AND m.is_read = 'false' AND m.sender_id <> @user
+) un ON true
+WHERE t.group_id = @group
+ORDER BY activity_at DESC, t.topic_id DESC`
+
+func (r *Repository) List(ctx context.Context, groupID, userID uint64) ([]model.GroupTopicSummary, error) {
+	var topics []model.GroupTopicSummary
+
+	result := r.db.WithContext(ctx).Raw(listQuery, map[string]interface{}{
+		"group": groupID,
+		"user":  userID,
+	}).Scan(&topics)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return topics, nil
+}
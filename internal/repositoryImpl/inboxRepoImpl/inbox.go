@@ -0,0 +1,184 @@
+package inboxRepoImpl
+
+import (
+	"backend/internal/model"
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const previewLength = 100
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// inboxQuery merges the user's private chats and groups, joins the peer or
+// group profile, the latest message and unread/mention counters, and the
+// user's conversation settings. Unread counts exclude the user's own
+// messages; a mention is an unread message containing "@username" as a whole
+// word, matched with the username escaped for use in a regular expression.
+const inboxQuery = `
+WITH me AS (
+	SELECT '(^|[^[:alnum:]_])@' || regexp_replace(username, '([^[:alnum:]_])', '\\\1', 'g') || '($|[^[:alnum:]_])' AS mention
+	FROM user_dtos WHERE user_id = @user
+), convs AS (
+	SELECT 'PV' AS type, c.chat_id,
+		CASE WHEN c.user_id = @user THEN c.receiver_id ELSE c.user_id END AS peer_id,
+		c.conversation_id, c.created_at
+	FROM user_chat_dtos c
+	WHERE c.user_id = @user OR c.receiver_id = @user
+	UNION ALL
+	SELECT 'GP', ug.group_id, 0, g.conversation_id, ug.created_at
+	FROM user_group_dtos ug
+	JOIN group_dtos g ON g.group_id = ug.group_id
+	WHERE ug.user_id = @user
+), items AS (
+	SELECT convs.type, convs.chat_id,
+		u.user_id AS peer_user_id, u.name AS peer_name, u.username AS peer_username,
+		u.profile_picture AS peer_profile_picture, u.is_active AS peer_is_active, u.last_seen AS peer_last_seen,
+		g.group_id AS group_id, g.name AS group_name, g.description AS group_description,
+		g.avatar AS group_avatar,
+		lm.message_id AS last_message_id, lm.sender_id AS last_sender_id, lm.kind AS last_kind,
+		lm.content AS last_content, lm.created_at AS last_created_at,
+		COALESCE(un.unread, 0) AS unread, COALESCE(un.mentions, 0) AS mentions,
+		COALESCE(s.pin_order, 0) AS pin_order, s.muted_until, COALESCE(s.marked_unread, false) AS marked_unread,
+		COALESCE(s.archived, false) AS archived,
+		COALESCE(lm.created_at, convs.created_at) AS activity_at
+	FROM convs
+	LEFT JOIN user_dtos u ON convs.type = 'PV' AND u.user_id = convs.peer_id
+	LEFT JOIN group_dtos g ON convs.type = 'GP' AND g.group_id = convs.chat_id
+	LEFT JOIN conversation_setting_dtos s ON s.user_id = @user AND s.type = convs.type AND s.chat_id = convs.chat_id
+	LEFT JOIN LATERAL (
+		SELECT m.message_id, m.sender_id, m.kind, m.content, m.created_at
+		FROM message_dtos m
+		WHERE m.conversation_id = convs.conversation_id
+		ORDER BY m.message_id DESC
+		LIMIT 1
+	) lm ON true
+	LEFT JOIN LATERAL (
+		SELECT COUNT(*) AS unread,
+			COUNT(*) FILTER (WHERE m.content ~* (SELECT mention FROM me)) AS mentions
+		FROM message_dtos m
+		WHERE m.conversation_id = convs.conversation_id AND m.is_read = 'false' AND m.sender_id <> @user
+	) un ON true
+)
+SELECT * FROM items
+WHERE archived = @archived AND (pin_order > 0) = @pinned
+	AND (@first OR (activity_at, type, chat_id) < (@cursor_at, @cursor_type, @cursor_chat))
+ORDER BY CASE WHEN @pinned THEN pin_order ELSE 0 END, activity_at DESC, type DESC, chat_id DESC
+LIMIT @limit`
+
+type inboxRow struct {
+	Type               model.MessageType
+	ChatID             uint64
+	PeerUserID         *uint64
+	PeerName           string
+	PeerUsername       string
+	PeerProfilePicture string
+	PeerIsActive       string
+	PeerLastSeen       *time.Time
+	GroupID            *uint64
+	GroupName          string
+	GroupDescription   string
+	GroupAvatar        string
+	LastMessageID      *uint64
+	LastSenderID       uint64
+	LastKind           model.ContentKind
+	LastContent        string
+	LastCreatedAt      time.Time
+	Unread             int64
+	Mentions           int64
+	PinOrder           int
+	MutedUntil         *time.Time
+	MarkedUnread       bool
+	ActivityAt         time.Time
+}
+
+func preview(content string) string {
+	if utf8.RuneCountInString(content) <= previewLength {
+		return content
+	}
+
+	return string([]rune(content)[:previewLength]) + "…"
+}
+
+func (r *Repository) List(ctx context.Context, iq model.InboxQuery) ([]model.InboxItem, error) {
+	args := map[string]interface{}{
+		"user":        iq.UserID,
+		"archived":    iq.Archived,
+		"pinned":      iq.Pinned,
+		"first":       iq.Cursor == nil,
+		"cursor_at":   time.Time{},
+		"cursor_type": "",
+		"cursor_chat": uint64(0),
+		"limit":       iq.Limit,
+	}
+	if iq.Cursor != nil {
+		args["cursor_at"] = iq.Cursor.ActivityAt
+		args["cursor_type"] = string(iq.Cursor.Type)
+		args["cursor_chat"] = iq.Cursor.ChatID
+	}
+
+	var rows []inboxRow
+	result := r.db.WithContext(ctx).Raw(inboxQuery, args).Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	items := make([]model.InboxItem, len(rows))
+	for i, row := range rows {
+		item := model.InboxItem{
+			Type:         row.Type,
+			ChatID:       row.ChatID,
+			Unread:       row.Unread,
+			Mentions:     row.Mentions,
+			PinOrder:     row.PinOrder,
+			MutedUntil:   row.MutedUntil,
+			MarkedUnread: row.MarkedUnread,
+			ActivityAt:   row.ActivityAt,
+		}
+
+		if row.PeerUserID != nil {
+			item.Peer = &model.InboxPeer{
+				UserID:         *row.PeerUserID,
+				Name:           row.PeerName,
+				Username:       row.PeerUsername,
+				ProfilePicture: row.PeerProfilePicture,
+				IsActive:       row.PeerIsActive,
+			}
+			if row.PeerLastSeen != nil {
+				item.Peer.LastSeen = *row.PeerLastSeen
+			}
+		}
+		if row.GroupID != nil {
+			item.Group = &model.InboxGroup{
+				GroupID:     *row.GroupID,
+				Name:        row.GroupName,
+				Description: row.GroupDescription,
+				Avatar:      row.GroupAvatar,
+			}
+		}
+		if row.LastMessageID != nil {
+			item.LastMessage = &model.InboxMessage{
+				MessageID: *row.LastMessageID,
+				SenderID:  row.LastSenderID,
+				Kind:      row.LastKind,
+				Preview:   preview(row.LastContent),
+				CreatedAt: row.LastCreatedAt,
+			}
+		}
+
+		items[i] = item
+	}
+
+	return items, nil
+}
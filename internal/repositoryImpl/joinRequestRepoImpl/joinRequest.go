@@ -0,0 +1,80 @@
+package joinRequestRepoImpl
+
+import (
+	"backend/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func ToJoinRequestDTO(request model.JoinRequest) *model.JoinRequestDTO {
+	return &model.JoinRequestDTO{
+		JoinRequest: request,
+		CreatedAt:   time.Now(),
+	}
+}
+
+func (r *Repository) Create(ctx context.Context, request model.JoinRequest) (uint64, error) {
+	requestDTO := ToJoinRequestDTO(request)
+
+	result := r.db.WithContext(ctx).Create(requestDTO)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return requestDTO.RequestID, nil
+}
+
+func (r *Repository) Get(ctx context.Context, jri model.JoinRequestInterface) ([]model.JoinRequestDTO, error) {
+	var requestDTOs []model.JoinRequestDTO
+	var condition model.JoinRequestDTO
+
+	if jri.ID != nil {
+		condition.RequestID = *jri.ID
+	}
+	if jri.GroupID != nil {
+		condition.GroupID = *jri.GroupID
+	}
+	if jri.UserID != nil {
+		condition.UserID = *jri.UserID
+	}
+	if jri.Status != nil {
+		condition.Status = *jri.Status
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Order("request_id").Find(&requestDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return requestDTOs, nil
+}
+
+func (r *Repository) Decide(ctx context.Context, requestID uint64, status model.JoinRequestStatus, decidedBy uint64) (bool, error) {
+	now := time.Now()
+
+	result := r.db.WithContext(ctx).Model(&model.JoinRequestDTO{}).
+		Where("request_id = ? AND status = ?", requestID, model.JoinPending).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"decided_by": decidedBy,
+			"decided_at": now,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
@@ -2,7 +2,11 @@ package messageRepoImpl
 
 import (
 	"backend/internal/model"
+	"backend/internal/repositoryImpl/conversationRepoImpl"
 	"context"
+	"errors"
+	"html"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -14,8 +18,9 @@ type Repository struct {
 
 type MessageDTO struct {
 	model.Message
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	SearchVector string    `gorm:"->;type:tsvector GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED;index:idx_message_search,type:gin" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 func New(db *gorm.DB) *Repository {
@@ -26,24 +31,36 @@ func New(db *gorm.DB) *Repository {
 
 func (m *MessageDTO) ToMessage() *model.Message {
 	return &model.Message{
-		MessageID: m.MessageID,
-		ChatID:    m.ChatID,
-		SenderID:  m.SenderID,
-		Content:   m.Content,
-		Type:      m.Type,
-		IsRead:    m.IsRead,
+		MessageID:      m.MessageID,
+		ConversationID: m.ConversationID,
+		ChatID:         m.ChatID,
+		TopicID:        m.TopicID,
+		SenderID:       m.SenderID,
+		Content:        m.Content,
+		Payload:        m.Payload,
+		Kind:           m.Kind,
+		ReplyToID:      m.ReplyToID,
+		Type:           m.Type,
+		IsRead:         m.IsRead,
 	}
 }
 
 func ToMessageDTO(message model.Message) *MessageDTO {
+	message.Payload = model.NormalizePayload(message.Payload)
+
 	return &MessageDTO{
 		Message: model.Message{
-			MessageID: message.MessageID,
-			ChatID:    message.ChatID,
-			SenderID:  message.SenderID,
-			Content:   message.Content,
-			Type:      message.Type,
-			IsRead:    message.IsRead,
+			MessageID:      message.MessageID,
+			ConversationID: message.ConversationID,
+			ChatID:         message.ChatID,
+			TopicID:        message.TopicID,
+			SenderID:       message.SenderID,
+			Content:        message.Content,
+			Payload:        message.Payload,
+			Kind:           message.Kind,
+			ReplyToID:      message.ReplyToID,
+			Type:           message.Type,
+			IsRead:         message.IsRead,
 		},
 		CreatedAt: time.Now(),
 	}
@@ -52,14 +69,108 @@ func ToMessageDTO(message model.Message) *MessageDTO {
 func (m *Repository) Create(ctx context.Context, message model.Message) (uint64, error) {
 	messageDTO := ToMessageDTO(message)
 
-	result := m.db.WithContext(ctx).Create(messageDTO)
-	if result.Error != nil {
-		return 0, result.Error
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		conversationID, err := conversationRepoImpl.Lookup(tx, message.Type, message.ChatID)
+		if err != nil {
+			return err
+		}
+		messageDTO.ConversationID = conversationID
+
+		return tx.Create(messageDTO).Error
+	})
+	if err != nil {
+		return 0, err
 	}
 
 	return messageDTO.MessageID, nil
 }
 
+// CreateBatch inserts messages keeping their CreatedAt, which lets imported
+// history retain its original timestamps.
+func (m *Repository) CreateBatch(ctx context.Context, messages []model.MessageDTO) ([]uint64, error) {
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	messageDTOs := make([]MessageDTO, len(messages))
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		conversations := make(map[model.Conversation]uint64)
+		for i, message := range messages {
+			dto := ToMessageDTO(message.Message)
+			dto.MessageID = 0
+
+			key := model.Conversation{Type: message.Type, ChatID: message.ChatID}
+			if _, ok := conversations[key]; !ok {
+				conversationID, err := conversationRepoImpl.Lookup(tx, message.Type, message.ChatID)
+				if err != nil {
+					return err
+				}
+				conversations[key] = conversationID
+			}
+			dto.ConversationID = conversations[key]
+			if !message.CreatedAt.IsZero() {
+				dto.CreatedAt = message.CreatedAt
+			}
+			dto.UpdatedAt = dto.CreatedAt
+			messageDTOs[i] = *dto
+		}
+
+		return tx.CreateInBatches(messageDTOs, 500).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint64, len(messageDTOs))
+	for i, dto := range messageDTOs {
+		ids[i] = dto.MessageID
+	}
+
+	return ids, nil
+}
+
+// SetReplies points each message in replies (message id -> replied message
+// id) at its parent without touching updated_at.
+func (m *Repository) SetReplies(ctx context.Context, replies map[uint64]uint64) error {
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for messageID, replyToID := range replies {
+			result := tx.Model(&MessageDTO{}).Where("message_id = ?", messageID).UpdateColumn("reply_to_id", replyToID)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		return nil
+	})
+}
+
+// messages selects messages together with the type and chat of their
+// conversation, which are not stored on the message.
+func (m *Repository) messages(ctx context.Context) *gorm.DB {
+	return m.db.WithContext(ctx).Model(&MessageDTO{}).
+		Select("message_dtos.*, c.type, c.chat_id").
+		Joins("JOIN conversation_dtos c ON c.conversation_id = message_dtos.conversation_id")
+}
+
+// inConversation narrows query to the conversations mi names by type and
+// chat.
+func (m *Repository) inConversation(query *gorm.DB, mi model.MessageInterface) *gorm.DB {
+	if mi.Type == nil && mi.ChatID == nil {
+		return query
+	}
+
+	var condition model.ConversationDTO
+	if mi.Type != nil {
+		condition.Type = *mi.Type
+	}
+	if mi.ChatID != nil {
+		condition.ChatID = *mi.ChatID
+	}
+
+	return query.Where("message_dtos.conversation_id IN (?)",
+		m.db.Model(&model.ConversationDTO{}).Select("conversation_id").Where(&condition))
+}
+
 func (m *Repository) Get(ctx context.Context, mi model.MessageInterface) ([]model.Message, error) {
 	var messageDTOs []MessageDTO
 	var condition MessageDTO
@@ -67,20 +178,25 @@ func (m *Repository) Get(ctx context.Context, mi model.MessageInterface) ([]mode
 	if mi.ID != nil {
 		condition.MessageID = *mi.ID
 	}
-	if mi.ChatID != nil {
-		condition.ChatID = *mi.ChatID
+	if mi.ConversationID != nil {
+		condition.ConversationID = *mi.ConversationID
 	}
 	if mi.SenderID != nil {
 		condition.SenderID = *mi.SenderID
 	}
-	if mi.Type != nil {
-		condition.Type = *mi.Type
+	if mi.Kind != nil {
+		condition.Kind = *mi.Kind
 	}
 	if mi.IsRead != nil {
 		condition.IsRead = *mi.IsRead
 	}
 
-	result := m.db.WithContext(ctx).Where(&condition).Find(&messageDTOs)
+	query := m.inConversation(m.messages(ctx).Where(&condition), mi)
+	if mi.TopicID != nil {
+		query = query.Where("topic_id = ?", *mi.TopicID)
+	}
+
+	result := query.Find(&messageDTOs)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -117,17 +233,22 @@ func (m *Repository) Delete(ctx context.Context, mi model.MessageInterface) erro
 	if mi.ID != nil {
 		condition.MessageID = *mi.ID
 	}
-	if mi.ChatID != nil {
-		condition.ChatID = *mi.ChatID
+	if mi.ConversationID != nil {
+		condition.ConversationID = *mi.ConversationID
 	}
 	if mi.SenderID != nil {
 		condition.SenderID = *mi.SenderID
 	}
-	if mi.Type != nil {
-		condition.Type = *mi.Type
+	if mi.Kind != nil {
+		condition.Kind = *mi.Kind
 	}
 
-	result := m.db.WithContext(ctx).Where(&condition).Delete(&MessageDTO{})
+	query := m.inConversation(m.db.WithContext(ctx).Where(&condition), mi)
+	if mi.TopicID != nil {
+		query = query.Where("topic_id = ?", *mi.TopicID)
+	}
+
+	result := query.Delete(&MessageDTO{})
 	if result.Error != nil {
 		return result.Error
 	}
@@ -142,17 +263,22 @@ func (m *Repository) GetDto(ctx context.Context, mi model.MessageInterface) ([]m
 	if mi.ID != nil {
 		condition.MessageID = *mi.ID
 	}
-	if mi.ChatID != nil {
-		condition.ChatID = *mi.ChatID
+	if mi.ConversationID != nil {
+		condition.ConversationID = *mi.ConversationID
 	}
 	if mi.SenderID != nil {
 		condition.SenderID = *mi.SenderID
 	}
-	if mi.Type != nil {
-		condition.Type = *mi.Type
+	if mi.Kind != nil {
+		condition.Kind = *mi.Kind
+	}
+
+	query := m.inConversation(m.messages(ctx).Where(&condition), mi)
+	if mi.TopicID != nil {
+		query = query.Where("topic_id = ?", *mi.TopicID)
 	}
 
-	result := m.db.WithContext(ctx).Where(&condition).Find(&messageDTOs)
+	result := query.Find(&messageDTOs)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -173,3 +299,192 @@ func (m *Repository) GetDto(ctx context.Context, mi model.MessageInterface) ([]m
 
 	return otherMessageDTO, nil
 }
+
+func (m *Repository) GetPage(ctx context.Context, mi model.MessageInterface, before *uint64, limit int) ([]model.MessageDTO, error) {
+	var messageDTOs []MessageDTO
+	var condition MessageDTO
+
+	if mi.ConversationID != nil {
+		condition.ConversationID = *mi.ConversationID
+	}
+	if mi.SenderID != nil {
+		condition.SenderID = *mi.SenderID
+	}
+	if mi.Kind != nil {
+		condition.Kind = *mi.Kind
+	}
+
+	query := m.inConversation(m.messages(ctx).Where(&condition), mi)
+	if mi.TopicID != nil {
+		query = query.Where("topic_id = ?", *mi.TopicID)
+	}
+	if before != nil {
+		query = query.Where("message_id < ?", *before)
+	}
+
+	result := query.Order("message_id DESC").Limit(limit).Find(&messageDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	messages := make([]model.MessageDTO, len(messageDTOs))
+	for i, message := range messageDTOs {
+		messages[i] = model.MessageDTO{
+			Message:   message.Message,
+			CreatedAt: message.CreatedAt,
+			UpdatedAt: message.UpdatedAt,
+		}
+	}
+
+	return messages, nil
+}
+
+func (m *Repository) GetRange(ctx context.Context, mi model.MessageInterface, from, to *time.Time, after *uint64, limit int) ([]model.MessageDTO, error) {
+	var messageDTOs []MessageDTO
+	var condition MessageDTO
+
+	if mi.ConversationID != nil {
+		condition.ConversationID = *mi.ConversationID
+	}
+	if mi.SenderID != nil {
+		condition.SenderID = *mi.SenderID
+	}
+	if mi.Kind != nil {
+		condition.Kind = *mi.Kind
+	}
+
+	query := m.inConversation(m.messages(ctx).Where(&condition), mi)
+	if from != nil {
+		query = query.Where("message_dtos.created_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("message_dtos.created_at < ?", *to)
+	}
+	if after != nil {
+		query = query.Where("message_id > ?", *after)
+	}
+
+	result := query.Order("message_id ASC").Limit(limit).Find(&messageDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	messages := make([]model.MessageDTO, len(messageDTOs))
+	for i, message := range messageDTOs {
+		messages[i] = model.MessageDTO{
+			Message:   message.Message,
+			CreatedAt: message.CreatedAt,
+			UpdatedAt: message.UpdatedAt,
+		}
+	}
+
+	return messages, nil
+}
+
+// CountUnread returns the number of unread messages not sent by userID in
+// each of the given conversations, in a single query.
+func (m *Repository) CountUnread(ctx context.Context, userID uint64, chatType model.MessageType, chatIDs []uint64) (map[uint64]int64, error) {
+	counts := make(map[uint64]int64, len(chatIDs))
+	if len(chatIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ChatID uint64
+		Unread int64
+	}
+
+	result := m.db.WithContext(ctx).Model(&MessageDTO{}).
+		Select("c.chat_id, COUNT(*) AS unread").
+		Joins("JOIN conversation_dtos c ON c.conversation_id = message_dtos.conversation_id").
+		Where("c.type = ? AND c.chat_id IN ? AND message_dtos.is_read = ? AND message_dtos.sender_id <> ?",
+			chatType, chatIDs, "false", userID).
+		Group("c.chat_id").
+		Find(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	for _, row := range rows {
+		counts[row.ChatID] = row.Unread
+	}
+
+	return counts, nil
+}
+
+func (m *Repository) Search(ctx context.Context, ms model.MessageSearch) ([]model.MessageSearchResult, error) {
+	query := m.db.WithContext(ctx).
+		Table("message_dtos AS m JOIN conversation_dtos c ON c.conversation_id = m.conversation_id, "+
+			"websearch_to_tsquery('simple', ?) AS q", ms.Query).
+		Select("m.message_id, c.chat_id, m.sender_id, c.type, m.kind, m.created_at, " +
+			"ts_headline('simple', translate(m.content, chr(2) || chr(3), ''), q, " +
+			"'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2') AS snippet, " +
+			"ts_rank(m.search_vector, q) AS rank").
+		Where("m.search_vector @@ q")
+
+	// Chat, group and channel ids overlap, so one conversation is only ever
+	// named by its type and id together, and checked against the membership
+	// of that kind alone.
+	switch {
+	case ms.ChatID != nil && ms.Type != nil && *ms.Type == model.TypePV:
+		query = query.Where("m.conversation_id IN (SELECT conversation_id FROM user_chat_dtos "+
+			"WHERE chat_id = ? AND (user_id = ? OR receiver_id = ?))", *ms.ChatID, ms.UserID, ms.UserID)
+	case ms.ChatID != nil && ms.Type != nil && *ms.Type == model.TypeGP:
+		query = query.Where("m.conversation_id IN (SELECT g.conversation_id FROM group_dtos g "+
+			"JOIN user_group_dtos ug ON ug.group_id = g.group_id WHERE g.group_id = ? AND ug.user_id = ?)", *ms.ChatID, ms.UserID)
+	case ms.ChatID != nil && ms.Type != nil && *ms.Type == model.TypeCH:
+		query = query.Where("m.conversation_id IN (SELECT ch.conversation_id FROM channel_dtos ch "+
+			"JOIN user_channel_dtos uc ON uc.channel_id = ch.channel_id WHERE ch.channel_id = ? AND uc.user_id = ?)", *ms.ChatID, ms.UserID)
+	case ms.ChatID != nil:
+		return nil, errors.New("a chat id needs its type")
+	default:
+		query = query.Where("m.conversation_id IN (SELECT conversation_id FROM user_chat_dtos WHERE user_id = ? OR receiver_id = ? "+
+			"UNION ALL SELECT g.conversation_id FROM group_dtos g JOIN user_group_dtos ug ON ug.group_id = g.group_id WHERE ug.user_id = ? "+
+			"UNION ALL SELECT ch.conversation_id FROM channel_dtos ch JOIN user_channel_dtos uc ON uc.channel_id = ch.channel_id WHERE uc.user_id = ?)",
+			ms.UserID, ms.UserID, ms.UserID, ms.UserID)
+		if ms.Type != nil {
+			query = query.Where("c.type = ?", *ms.Type)
+		}
+	}
+	if ms.Kind != nil {
+		query = query.Where("m.kind = ?", *ms.Kind)
+	}
+	if ms.SenderID != nil {
+		query = query.Where("m.sender_id = ?", *ms.SenderID)
+	}
+	if ms.From != nil {
+		query = query.Where("m.created_at >= ?", *ms.From)
+	}
+	if ms.To != nil {
+		query = query.Where("m.created_at < ?", *ms.To)
+	}
+
+	ranked := m.db.WithContext(ctx).Table("(?) AS r", query)
+	if ms.Cursor != nil {
+		ranked = ranked.Where("(r.rank < ? OR (r.rank = ? AND r.message_id < ?))",
+			ms.Cursor.Rank, ms.Cursor.Rank, ms.Cursor.MessageID)
+	}
+
+	var results []model.MessageSearchResult
+	result := ranked.Order("r.rank DESC, r.message_id DESC").Limit(ms.Limit).Find(&results)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	for i := range results {
+		results[i].Snippet = highlight(results[i].Snippet)
+	}
+
+	return results, nil
+}
+
+// highlightMarks turns the control characters ts_headline is told to put
+// around matches into markup. They are stripped from the content first, so
+// only the headline can produce them.
+var highlightMarks = strings.NewReplacer("\x02", "<b>", "\x03", "</b>")
+
+// highlight escapes a snippet so it is safe to render as HTML, then marks
+// the matches in bold.
+func highlight(snippet string) string {
+	return highlightMarks.Replace(html.EscapeString(snippet))
+}
@@ -0,0 +1,89 @@
+package settingRepoImpl
+
+import (
+	"backend/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (s *Repository) Get(ctx context.Context, csi model.ConversationSettingInterface) ([]model.ConversationSetting, error) {
+	var settingDTOs []model.ConversationSettingDTO
+	var condition model.ConversationSettingDTO
+
+	if csi.UserID != nil {
+		condition.UserID = *csi.UserID
+	}
+	if csi.Type != nil {
+		condition.Type = *csi.Type
+	}
+	if csi.ChatID != nil {
+		condition.ChatID = *csi.ChatID
+	}
+
+	result := s.db.WithContext(ctx).Where(&condition).Find(&settingDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	settings := make([]model.ConversationSetting, len(settingDTOs))
+	for i, settingDTO := range settingDTOs {
+		settings[i] = settingDTO.ConversationSetting
+	}
+
+	return settings, nil
+}
+
+// Save creates or fully overwrites the settings a user has for one
+// conversation.
+func (s *Repository) Save(ctx context.Context, setting model.ConversationSetting) error {
+	setting.SettingID = 0
+	dto := model.ConversationSettingDTO{
+		ConversationSetting: setting,
+		CreatedAt:           time.Now(),
+		UpdatedAt:           time.Now(),
+	}
+
+	result := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "chat_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"archived", "pin_order", "muted_until", "marked_unread", "updated_at"}),
+	}).Create(&dto)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (s *Repository) Delete(ctx context.Context, csi model.ConversationSettingInterface) error {
+	var condition model.ConversationSettingDTO
+
+	if csi.UserID != nil {
+		condition.UserID = *csi.UserID
+	}
+	if csi.Type != nil {
+		condition.Type = *csi.Type
+	}
+	if csi.ChatID != nil {
+		condition.ChatID = *csi.ChatID
+	}
+
+	result := s.db.WithContext(ctx).Where(&condition).Delete(&model.ConversationSettingDTO{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package userChannelRepoImpl
+
+import (
+	"backend/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Create(ctx context.Context, userChannel model.UserChannel) error {
+	dto := &model.UserChannelDTO{
+		UserChannel: userChannel,
+		CreatedAt:   time.Now(),
+	}
+
+	result := r.db.WithContext(ctx).Create(dto)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) Get(ctx context.Context, uci model.UserChannelInterface) ([]model.UserChannel, error) {
+	var userChannelDTOs []model.UserChannelDTO
+	var condition model.UserChannelDTO
+
+	if uci.ID != nil {
+		condition.UserChannelID = *uci.ID
+	}
+	if uci.UserID != nil {
+		condition.UserID = *uci.UserID
+	}
+	if uci.ChannelID != nil {
+		condition.ChannelID = *uci.ChannelID
+	}
+	if uci.Role != nil {
+		condition.Role = *uci.Role
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Find(&userChannelDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	userChannels := make([]model.UserChannel, len(userChannelDTOs))
+	for i, userChannelDTO := range userChannelDTOs {
+		userChannels[i] = userChannelDTO.UserChannel
+	}
+
+	return userChannels, nil
+}
+
+func (r *Repository) Update(ctx context.Context, userChannel model.UserChannel) error {
+	var condition model.UserChannelDTO
+	condition.UserChannelID = userChannel.UserChannelID
+
+	dto := model.UserChannelDTO{
+		UserChannel: userChannel,
+		UpdatedAt:   time.Now(),
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Updates(&dto)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) Delete(ctx context.Context, uci model.UserChannelInterface) error {
+	var condition model.UserChannelDTO
+
+	if uci.ID != nil {
+		condition.UserChannelID = *uci.ID
+	}
+	if uci.UserID != nil {
+		condition.UserID = *uci.UserID
+	}
+	if uci.ChannelID != nil {
+		condition.ChannelID = *uci.ChannelID
+	}
+
+	result := r.db.WithContext(ctx).Where(&condition).Delete(&model.UserChannelDTO{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) Count(ctx context.Context, channelID uint64) (int64, error) {
+	var count int64
+
+	result := r.db.WithContext(ctx).Model(&model.UserChannelDTO{}).Where("channel_id = ?", channelID).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return count, nil
+}
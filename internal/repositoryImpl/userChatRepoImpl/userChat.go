@@ -2,8 +2,10 @@ package userChatRepoImpl
 
 import (
 	"backend/internal/model"
+	"backend/internal/repositoryImpl/conversationRepoImpl"
 	"context"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"time"
 )
 
@@ -25,32 +27,46 @@ func New(db *gorm.DB) *Repository {
 
 func (u *UserChatDTO) ToUserChat() *model.Chat {
 	return &model.Chat{
-		ChatID:     u.ChatID,
-		UserID:     u.UserID,
-		ReceiverID: u.ReceiverID,
+		ChatID:         u.ChatID,
+		UserID:         u.UserID,
+		ReceiverID:     u.ReceiverID,
+		ConversationID: u.ConversationID,
 	}
 }
 
 func ToUserChatDTO(userChat model.Chat) *UserChatDTO {
 	return &UserChatDTO{
 		Chat: model.Chat{
-			ChatID:     userChat.ChatID,
-			UserID:     userChat.UserID,
-			ReceiverID: userChat.ReceiverID,
+			ChatID:         userChat.ChatID,
+			UserID:         userChat.UserID,
+			ReceiverID:     userChat.ReceiverID,
+			ConversationID: userChat.ConversationID,
 		},
 		CreatedAt: time.Now(),
 	}
 }
 
+// attachConversation gives a freshly inserted chat its conversation.
+func attachConversation(tx *gorm.DB, userChatDTO *UserChatDTO) error {
+	conversationID, err := conversationRepoImpl.Resolve(tx, model.TypePV, userChatDTO.ChatID)
+	if err != nil {
+		return err
+	}
+	userChatDTO.ConversationID = conversationID
+
+	return tx.Model(userChatDTO).UpdateColumn("conversation_id", conversationID).Error
+}
+
 func (u *Repository) Create(ctx context.Context, userChat model.Chat) error {
 	userChatDTO := ToUserChatDTO(userChat)
 
-	result := u.db.WithContext(ctx).Create(userChatDTO)
-	if result.Error != nil {
-		return result.Error
-	}
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(userChatDTO).Error; err != nil {
+			return err
+		}
 
-	return nil
+		return attachConversation(tx, userChatDTO)
+	})
 }
 
 func (u *Repository) Get(ctx context.Context, ci model.ChatInterface) ([]model.Chat, error) {
@@ -111,10 +127,75 @@ func (u *Repository) Delete(ctx context.Context, ci model.ChatInterface) error {
 		condition.ReceiverID = *ci.ReceiverID
 	}
 
-	result := u.db.WithContext(ctx).Where(&condition).Delete(&UserChatDTO{})
-	if result.Error != nil {
-		return result.Error
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var chats []UserChatDTO
+		if err := tx.Where(&condition).Find(&chats).Error; err != nil {
+			return err
+		}
+		if len(chats) == 0 {
+			return nil
+		}
+
+		chatIDs := make([]uint64, len(chats))
+		conversationIDs := make([]uint64, len(chats))
+		for i, chat := range chats {
+			chatIDs[i] = chat.ChatID
+			conversationIDs[i] = chat.ConversationID
+		}
+
+		if err := tx.Where("chat_id IN ?", chatIDs).Delete(&UserChatDTO{}).Error; err != nil {
+			return err
+		}
+
+		return conversationRepoImpl.Delete(tx, model.TypePV, chatIDs, conversationIDs)
+	})
+}
+
+// pairCondition matches the chat between a and b in either direction, the same
+// unordered pair the idx_chat_pair unique index is built on.
+const pairCondition = "LEAST(user_id, receiver_id) = LEAST(?::bigint, ?::bigint) AND GREATEST(user_id, receiver_id) = GREATEST(?::bigint, ?::bigint)"
+
+func (u *Repository) Open(ctx context.Context, userID, peerID uint64) (model.Chat, bool, error) {
+	db := u.db.WithContext(ctx)
+
+	var existing []UserChatDTO
+	if err := db.Where(pairCondition, userID, peerID, userID, peerID).Limit(1).Find(&existing).Error; err != nil {
+		return model.Chat{}, false, err
+	}
+	if len(existing) != 0 {
+		return *existing[0].ToUserChat(), false, nil
 	}
 
-	return nil
+	dto := ToUserChatDTO(model.Chat{
+		UserID:     userID,
+		ReceiverID: peerID,
+	})
+
+	// A concurrent Open for the same pair may win the race; the unique index
+	// turns our insert into a no-op and we read back the winner's row.
+	created := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(dto)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		created = true
+		return attachConversation(tx, dto)
+	})
+	if err != nil {
+		return model.Chat{}, false, err
+	}
+	if created {
+		return *dto.ToUserChat(), true, nil
+	}
+
+	if err := db.Where(pairCondition, userID, peerID, userID, peerID).Limit(1).Find(&existing).Error; err != nil {
+		return model.Chat{}, false, err
+	}
+	if len(existing) == 0 {
+		return model.Chat{}, false, gorm.ErrRecordNotFound
+	}
+
+	return *existing[0].ToUserChat(), false, nil
 }
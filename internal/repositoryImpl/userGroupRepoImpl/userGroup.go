@@ -2,10 +2,12 @@ package userGroupRepoImpl
 
 import (
 	"backend/internal/model"
+	"backend/utils"
 	"context"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
@@ -26,18 +28,32 @@ func New(db *gorm.DB) *Repository {
 
 func (u *UserGroupDTO) ToUserGroup() *model.UserGroup {
 	return &model.UserGroup{
-		UserGroupID: u.UserGroupID,
-		UserID:      u.UserID,
-		GroupID:     u.GroupID,
+		UserGroupID:      u.UserGroupID,
+		UserID:           u.UserID,
+		GroupID:          u.GroupID,
+		Role:             u.Role,
+		InviteID:         u.InviteID,
+		CommunityID:      u.CommunityID,
+		GroupPermissions: u.GroupPermissions,
+		ReadOnlyUntil:    u.ReadOnlyUntil,
+		NoMediaUntil:     u.NoMediaUntil,
+		RulesAcceptedAt:  u.RulesAcceptedAt,
 	}
 }
 
 func ToUserGroupDTO(userGroup model.UserGroup) *UserGroupDTO {
 	return &UserGroupDTO{
 		UserGroup: model.UserGroup{
-			UserGroupID: userGroup.UserGroupID,
-			UserID:      userGroup.UserID,
-			GroupID:     userGroup.GroupID,
+			UserGroupID:      userGroup.UserGroupID,
+			UserID:           userGroup.UserID,
+			GroupID:          userGroup.GroupID,
+			Role:             userGroup.Role,
+			InviteID:         userGroup.InviteID,
+			CommunityID:      userGroup.CommunityID,
+			GroupPermissions: userGroup.GroupPermissions,
+			ReadOnlyUntil:    userGroup.ReadOnlyUntil,
+			NoMediaUntil:     userGroup.NoMediaUntil,
+			RulesAcceptedAt:  userGroup.RulesAcceptedAt,
 		},
 		CreatedAt: time.Now(),
 	}
@@ -46,7 +62,10 @@ func ToUserGroupDTO(userGroup model.UserGroup) *UserGroupDTO {
 func (r *Repository) Create(ctx context.Context, userGroup model.UserGroup) error {
 	userGroupDTO := ToUserGroupDTO(userGroup)
 
-	result := r.db.WithContext(ctx).Create(userGroupDTO)
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_id"}, {Name: "user_id"}},
+		DoNothing: true,
+	}).Create(userGroupDTO)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -67,6 +86,12 @@ func (r *Repository) Get(ctx context.Context, ugi model.UserGroupInterface) ([]m
 	if ugi.UserID != nil {
 		condition.UserID = *ugi.UserID
 	}
+	if ugi.Role != nil {
+		condition.Role = *ugi.Role
+	}
+	if ugi.InviteID != nil {
+		condition.InviteID = *ugi.InviteID
+	}
 
 	result := r.db.WithContext(ctx).Where(&condition).Find(&userGroupDTOs)
 	if result.Error != nil {
@@ -134,3 +159,101 @@ func (r *Repository) GetGroupWithUserGroups(ctx context.Context, groupID uint64)
 
 	return group, userGroups, nil
 }
+
+func (r *Repository) SetRole(ctx context.Context, userGroup model.UserGroup) error {
+	dto := UserGroupDTO{
+		UserGroup: userGroup,
+		UpdatedAt: time.Now(),
+	}
+
+	result := r.db.WithContext(ctx).Model(&UserGroupDTO{}).
+		Where("group_id = ? AND user_id = ?", userGroup.GroupID, userGroup.UserID).
+		Select("role", "can_add_members", "can_remove_members", "can_delete_messages", "can_pin_messages",
+			"can_edit_info", "can_manage_topics", "updated_at").
+		Updates(&dto)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) TransferOwnership(ctx context.Context, formerOwner, newOwner model.UserGroup) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		members := New(tx)
+		if err := members.SetRole(ctx, newOwner); err != nil {
+			return err
+		}
+		if err := members.SetRole(ctx, formerOwner); err != nil {
+			return err
+		}
+
+		return tx.Model(&model.GroupDTO{}).Where("group_id = ?", newOwner.GroupID).
+			Updates(map[string]interface{}{"creator": newOwner.UserID, "updated_at": time.Now()}).Error
+	})
+}
+
+func (r *Repository) Count(ctx context.Context, groupID uint64) (int64, error) {
+	var count int64
+
+	result := r.db.WithContext(ctx).Model(&UserGroupDTO{}).Where("group_id = ?", groupID).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return count, nil
+}
+
+func (r *Repository) SetRestrictions(ctx context.Context, userGroup model.UserGroup) error {
+	dto := UserGroupDTO{
+		UserGroup: userGroup,
+		UpdatedAt: time.Now(),
+	}
+
+	result := r.db.WithContext(ctx).Model(&UserGroupDTO{}).
+		Where("group_id = ? AND user_id = ?", userGroup.GroupID, userGroup.UserID).
+		Select("read_only_until", "no_media_until", "updated_at").
+		Updates(&dto)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) AcceptRules(ctx context.Context, groupID, userID uint64, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&UserGroupDTO{}).
+		Where("group_id = ? AND user_id = ?", groupID, userID).
+		Updates(map[string]interface{}{"rules_accepted_at": at, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *Repository) Members(ctx context.Context, q model.GroupMemberQuery) ([]model.GroupMemberProfile, error) {
+	var members []model.GroupMemberProfile
+
+	query := r.db.WithContext(ctx).Table("user_group_dtos ug").
+		Select("ug.user_group_id, ug.user_id, u.name, u.username, u.profile_picture, u.is_active, u.last_seen, "+
+			"ug.role, ug.can_add_members, ug.can_remove_members, ug.can_delete_messages, ug.can_pin_messages, "+
+			"ug.can_edit_info, ug.can_manage_topics, ug.created_at AS joined_at").
+		Joins("JOIN user_dtos u ON u.user_id = ug.user_id").
+		Where("ug.group_id = ? AND ug.user_group_id > ?", q.GroupID, q.After)
+
+	if q.Role != nil {
+		query = query.Where("ug.role = ?", *q.Role)
+	}
+	if q.Search != "" {
+		pattern := "%" + utils.EscapeLike(q.Search) + "%"
+		query = query.Where("(u.name ILIKE ? OR u.username ILIKE ?)", pattern, pattern)
+	}
+
+	result := query.Order("ug.user_group_id").Limit(q.Limit).Scan(&members)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return members, nil
+}
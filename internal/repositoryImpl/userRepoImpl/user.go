@@ -98,6 +98,26 @@ func (u *Repository) Get(ctx context.Context, ui model.UserInterface) ([]model.U
 	return users, nil
 }
 
+func (u *Repository) GetMany(ctx context.Context, userIDs []uint64) ([]model.User, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	var userDTOs []UserDTO
+
+	result := u.db.WithContext(ctx).Where("user_id IN ?", userIDs).Find(&userDTOs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	users := make([]model.User, len(userDTOs))
+	for i, userDTO := range userDTOs {
+		users[i] = *userDTO.ToUser()
+	}
+
+	return users, nil
+}
+
 func (u *Repository) Update(ctx context.Context, user model.User) error {
 	var condition UserDTO
 	condition.Username = user.Username
@@ -3,17 +3,15 @@ package main
 import (
 	"backend/api"
 	"backend/internal/configs"
+	"backend/internal/migrations"
 	"backend/internal/model"
 	"backend/internal/repositoryImpl/contactRepoImpl"
-	"backend/internal/repositoryImpl/groupChatRepoImpl"
 	"backend/internal/repositoryImpl/messageRepoImpl"
 	"backend/internal/repositoryImpl/userChatRepoImpl"
 	"backend/internal/repositoryImpl/userGroupRepoImpl"
 	"backend/internal/repositoryImpl/userRepoImpl"
+	"backend/utils/datasource"
 	"github.com/sirupsen/logrus"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
-	"strconv"
 )
 
 func main() {
@@ -22,18 +20,29 @@ func main() {
 		logrus.Warnln("error in loading configs")
 	}
 
-	port := strconv.Itoa(conf.Database.Port)
-	dsn := "host=" + conf.Database.Addr + " user=" + conf.Database.User + " password=" + conf.Database.Password + " dbname=" + conf.Database.DBName + " port=" + port
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := datasource.ConnectPostgres(conf.Database)
 	if err != nil {
 		logrus.Fatalf("failed to connect database %v", err)
 	}
 
+	if err = migrations.Prepare(db); err != nil {
+		logrus.Fatalf("failed to prepare database %v", err)
+	}
+
 	err = db.AutoMigrate(new(userRepoImpl.UserDTO), new(userChatRepoImpl.UserChatDTO), new(messageRepoImpl.MessageDTO), new(model.GroupDTO),
-		new(contactRepoImpl.ContactDTO), new(userGroupRepoImpl.UserGroupDTO), new(groupChatRepoImpl.GroupChatDTO))
+		new(contactRepoImpl.ContactDTO), new(userGroupRepoImpl.UserGroupDTO), new(model.ConversationDTO),
+		new(model.BookmarkDTO), new(model.ChannelDTO), new(model.UserChannelDTO), new(model.ChannelViewDTO),
+		new(model.FolderDTO), new(model.FolderEntryDTO), new(model.ConversationSettingDTO), new(model.GroupInviteDTO),
+		new(model.JoinRequestDTO), new(model.GroupBanDTO),
+		new(model.GroupProfileChangeDTO), new(model.GroupTopicDTO), new(model.CommunityDTO), new(model.UserCommunityDTO),
+		new(model.CommunityLinkDTO))
 	if err != nil {
 		return
 	}
 
+	if err = migrations.Run(db); err != nil {
+		logrus.Fatalf("failed to migrate database %v", err)
+	}
+
 	api.Run(db, conf)
 }
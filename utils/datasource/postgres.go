@@ -0,0 +1,23 @@
+package datasource
+
+import (
+	"backend/internal/configs"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func ConnectPostgres(conf configs.DBConfig) (*gorm.DB, error) {
+	port := strconv.Itoa(conf.Port)
+	dsn := "host=" + conf.Addr + " user=" + conf.User + " password=" + conf.Password + " dbname=" + conf.DBName + " port=" + port
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		log.Warnln("can not connect to postgres", err)
+		return nil, err
+	}
+
+	return db, nil
+}
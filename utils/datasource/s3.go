@@ -40,11 +40,16 @@ func UploadS3(sess *session.Session, fileHeader *multipart.FileHeader, bucket st
 	fullFileName := ID + fileHeader.Filename
 	key := fmt.Sprintf("%s", fullFileName)
 
-	_, err = uploader.Upload(&s3manager.UploadInput{
+	input := &s3manager.UploadInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 		Body:   file,
-	})
+	}
+	if contentType := fileHeader.Header.Get("Content-Type"); contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+
+	_, err = uploader.Upload(input)
 	if err != nil {
 		log.Warnf("Unable to upload %q to %q, %v", fullFileName, bucket, err)
 		return "", err
@@ -83,3 +88,21 @@ func DownloadS3(sess *session.Session, bucket string, key string) (*os.File, err
 
 	return file, nil
 }
+
+// OpenS3 returns the object stored under key and its content type. Unlike
+// DownloadS3 nothing is written to disk, so concurrent readers of the same
+// key cannot clobber each other. The caller closes the body.
+func OpenS3(sess *session.Session, bucket string, key string) (io.ReadCloser, string, error) {
+	s3Client := s3.New(sess)
+
+	obj, err := s3Client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		log.Warnf("Unable to download item %q, %v", key, err)
+		return nil, "", err
+	}
+
+	return obj.Body, aws.StringValue(obj.ContentType), nil
+}
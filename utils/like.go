@@ -0,0 +1,11 @@
+package utils
+
+import "strings"
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike escapes the LIKE wildcards in s so it matches literally. It
+// relies on backslash being the escape character, the Postgres default.
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,25 @@
+package utils
+
+import "testing"
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "", want: ""},
+		{in: "plain", want: "plain"},
+		{in: "100%", want: `100\%`},
+		{in: "snake_case", want: `snake\_case`},
+		{in: `a\b`, want: `a\\b`},
+		{in: `\%_`, want: `\\\%\_`},
+		{in: "%%", want: `\%\%`},
+		{in: "naïve_ü", want: `naïve\_ü`},
+	}
+
+	for _, tt := range tests {
+		if got := EscapeLike(tt.in); got != tt.want {
+			t.Errorf("EscapeLike(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}